@@ -0,0 +1,299 @@
+// Package attest produces and verifies in-toto attestations recording that a
+// pull request passed the review gates enforced by the bot.
+//
+// Statements are wrapped in a DSSE envelope and signed with an OpenPGP key.
+package attest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+const (
+	// StatementType is the in-toto statement type produced by this package.
+	StatementType = "https://in-toto.io/Statement/v0.1"
+	// PredicateType identifies the pull request review predicate.
+	PredicateType = "https://github.com/gravitational/gh-actions-poc/attestations/pull-request-review/v1"
+	// PayloadType is the DSSE payload type of an in-toto statement.
+	PayloadType = "application/vnd.in-toto+json"
+
+	// digestGitCommit is the in-toto digest algorithm name for git commits.
+	digestGitCommit = "gitCommit"
+)
+
+// Statement is an in-toto statement about a pull request.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is the artifact a statement is about.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate records the outcome of the review gates for a pull request.
+type Predicate struct {
+	// Repository is the repository in "owner/name" form.
+	Repository string `json:"repository"`
+	// PullRequest is the pull request number.
+	PullRequest int `json:"pullRequest"`
+	// HeadSHA is the commit the pull request was evaluated at.
+	HeadSHA string `json:"headSHA"`
+	// Commits is the verification result of every commit in the pull request.
+	Commits []Commit `json:"commits"`
+	// Approvers are the reviewers whose approval counted towards the
+	// policy. Expired approvals and approvals from reviewers no requirement
+	// draws on are left out.
+	Approvers []string `json:"approvers"`
+	// Policy is the review policy the pull request was evaluated against.
+	Policy Policy `json:"policy"`
+	// Timestamp is when the pull request was evaluated.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Commit is the signature verification result of a single commit.
+type Commit struct {
+	SHA      string `json:"sha"`
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason"`
+}
+
+// Policy describes the review policy a pull request was evaluated against.
+type Policy struct {
+	Name         string        `json:"name"`
+	Requirements []Requirement `json:"requirements"`
+}
+
+// Requirement is a single rule of a policy and whether it was met.
+type Requirement struct {
+	Description string `json:"description"`
+	Satisfied   bool   `json:"satisfied"`
+}
+
+// Satisfied returns true if every requirement of the policy was met.
+func (p Policy) Satisfied() bool {
+	for _, r := range p.Requirements {
+		if !r.Satisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// NewStatement returns a statement with the given predicate about the head
+// commit of a pull request.
+func NewStatement(predicate Predicate) *Statement {
+	return &Statement{
+		Type: StatementType,
+		Subject: []Subject{{
+			Name:   fmt.Sprintf("https://github.com/%v/pull/%v", predicate.Repository, predicate.PullRequest),
+			Digest: map[string]string{digestGitCommit: predicate.HeadSHA},
+		}},
+		PredicateType: PredicateType,
+		Predicate:     predicate,
+	}
+}
+
+// Envelope is a DSSE envelope carrying a signed statement.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is an OpenPGP signature over the envelope payload.
+type Signature struct {
+	// KeyID is the fingerprint of the signing key.
+	KeyID string `json:"keyid"`
+	// Sig is the base64 encoded detached OpenPGP signature.
+	Sig string `json:"sig"`
+}
+
+// Sign serializes the statement and signs it with the signing key of the
+// given entity: its newest valid signing subkey, or else its primary key.
+func Sign(statement *Statement, signer *openpgp.Entity) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	key, err := signingKey(signer, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// DetachSign always signs with the entity's primary private key, so
+	// hand it the chosen key in its place.
+	var sig bytes.Buffer
+	if err := openpgp.DetachSign(&sig, &openpgp.Entity{PrimaryKey: &key.PublicKey, PrivateKey: key}, bytes.NewReader(pae(PayloadType, payload)), nil); err != nil {
+		return nil, fmt.Errorf("signing statement: %w", err)
+	}
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{{
+			KeyID: hex.EncodeToString(key.PublicKey.Fingerprint[:]),
+			Sig:   base64.StdEncoding.EncodeToString(sig.Bytes()),
+		}},
+	}, nil
+}
+
+// signingKey returns the private key of an entity that verifiers accept
+// signatures from: the newest unexpired subkey flagged for signing, or the
+// primary key if none is and its flags allow signing.
+func signingKey(entity *openpgp.Entity, now time.Time) (*packet.PrivateKey, error) {
+	var key *openpgp.Subkey
+	for i, subkey := range entity.Subkeys {
+		if subkey.PrivateKey == nil || !subkey.Sig.FlagsValid || !subkey.Sig.FlagSign || subkey.Sig.KeyExpired(now) {
+			continue
+		}
+		if key == nil || subkey.PublicKey.CreationTime.After(key.PublicKey.CreationTime) {
+			key = &entity.Subkeys[i]
+		}
+	}
+	if key != nil {
+		return key.PrivateKey, nil
+	}
+	for _, identity := range entity.Identities {
+		if sig := identity.SelfSignature; sig.FlagsValid && !sig.FlagSign {
+			return nil, fmt.Errorf("key %X has no signing key", entity.PrimaryKey.Fingerprint)
+		}
+	}
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("key %X has no private key", entity.PrimaryKey.Fingerprint)
+	}
+	return entity.PrivateKey, nil
+}
+
+// Verify checks that the envelope was signed by a key in the keyring and
+// returns the statement it carries.
+func Verify(envelope *Envelope, keyring openpgp.KeyRing) (*Statement, error) {
+	if envelope.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decoding payload: %w", err)
+	}
+	if !verifyAny(envelope.Signatures, keyring, pae(envelope.PayloadType, payload)) {
+		return nil, fmt.Errorf("no valid signature from a trusted key")
+	}
+
+	var statement Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("decoding statement: %w", err)
+	}
+	if statement.Type != StatementType {
+		return nil, fmt.Errorf("unexpected statement type %q", statement.Type)
+	}
+	if statement.PredicateType != PredicateType {
+		return nil, fmt.Errorf("unexpected predicate type %q", statement.PredicateType)
+	}
+	return &statement, nil
+}
+
+// verifyAny returns true if any of the signatures over message is valid.
+func verifyAny(signatures []Signature, keyring openpgp.KeyRing, message []byte) bool {
+	for _, s := range signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(message), bytes.NewReader(sig)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// HeadSHA returns the commit the statement is about.
+func (s *Statement) HeadSHA() string {
+	for _, subject := range s.Subject {
+		if sha, ok := subject.Digest[digestGitCommit]; ok {
+			return sha
+		}
+	}
+	return ""
+}
+
+// pae is the DSSE pre-authentication encoding of a payload.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// ReadSigningKey reads the first key from an armored private key file,
+// decrypting it and its subkeys with passphrase if they are encrypted.
+func ReadSigningKey(path string, passphrase []byte) (*openpgp.Entity, error) {
+	keyring, err := ReadKeyRing(path)
+	if err != nil {
+		return nil, err
+	}
+	signer := keyring[0]
+	if signer.PrivateKey == nil {
+		return nil, fmt.Errorf("%v does not contain a private key", path)
+	}
+	if err := decrypt(signer.PrivateKey, passphrase); err != nil {
+		return nil, fmt.Errorf("decrypting signing key: %w", err)
+	}
+	for _, subkey := range signer.Subkeys {
+		if subkey.PrivateKey == nil {
+			continue
+		}
+		if err := decrypt(subkey.PrivateKey, passphrase); err != nil {
+			return nil, fmt.Errorf("decrypting subkey %X: %w", subkey.PublicKey.Fingerprint, err)
+		}
+	}
+	return signer, nil
+}
+
+// decrypt decrypts a private key if it is encrypted.
+func decrypt(key *packet.PrivateKey, passphrase []byte) error {
+	if !key.Encrypted {
+		return nil
+	}
+	return key.Decrypt(passphrase)
+}
+
+// ReadKeyRing reads an armored key ring from a file.
+func ReadKeyRing(path string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keyring, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading key ring %v: %w", path, err)
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("%v does not contain any keys", path)
+	}
+	return keyring, nil
+}
+
+// WriteEnvelope writes an envelope as indented JSON.
+func WriteEnvelope(w io.Writer, envelope *Envelope) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(envelope)
+}
+
+// ReadEnvelope reads a JSON encoded envelope.
+func ReadEnvelope(r io.Reader) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("decoding envelope: %w", err)
+	}
+	return &envelope, nil
+}
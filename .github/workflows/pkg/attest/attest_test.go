@@ -0,0 +1,82 @@
+package attest
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestSignVerify(t *testing.T) {
+	// The key has a certification-only primary key and a signing subkey,
+	// both encrypted with the passphrase.
+	signer, err := ReadSigningKey("testdata/signing-key.asc", []byte("test-passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := ReadKeyRing("testdata/public-key.asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement := NewStatement(Predicate{
+		Repository:  "gravitational/teleport",
+		PullRequest: 7,
+		HeadSHA:     "0123456789abcdef0123456789abcdef01234567",
+		Approvers:   []string{"alice", "bob"},
+		Timestamp:   time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC),
+	})
+
+	envelope, err := Sign(statement, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteEnvelope(&buf, envelope); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadEnvelope(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := Verify(read, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(verified, statement) {
+		t.Errorf("Verify() = %+v, want %+v", verified, statement)
+	}
+	if verified.HeadSHA() != statement.Predicate.HeadSHA {
+		t.Errorf("HeadSHA() = %v, want %v", verified.HeadSHA(), statement.Predicate.HeadSHA)
+	}
+
+	tampered := *read
+	tampered.Payload = envelope.Payload[:len(envelope.Payload)-4] + "AAAA"
+	if _, err := Verify(&tampered, keyring); err == nil {
+		t.Error("Verify() of a tampered payload succeeded")
+	}
+}
+
+func TestReadSigningKeyWrongPassphrase(t *testing.T) {
+	if _, err := ReadSigningKey("testdata/signing-key.asc", []byte("wrong")); err == nil {
+		t.Error("ReadSigningKey() with the wrong passphrase succeeded")
+	}
+}
+
+func TestSignWithPrimaryKey(t *testing.T) {
+	// New entities sign with their primary key and only encrypt with their
+	// subkey.
+	signer, err := openpgp.NewEntity("Attest Test", "", "attest@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement := NewStatement(Predicate{Repository: "gravitational/teleport", PullRequest: 7, HeadSHA: "0123456789abcdef"})
+	envelope, err := Sign(statement, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(envelope, openpgp.EntityList{signer}); err != nil {
+		t.Error(err)
+	}
+}
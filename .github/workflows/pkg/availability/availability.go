@@ -0,0 +1,169 @@
+// Package availability reads the calendar of dates reviewers are away, so
+// that reviews are not requested from people who cannot respond.
+package availability
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Path is the location of the calendar in the repository.
+const Path = ".github/reviewer-availability.yaml"
+
+// dateLayout is the layout of dates in the calendar.
+const dateLayout = "2006-01-02"
+
+// Calendar lists the dates each reviewer is unavailable.
+//
+//	reviewers:
+//	  alice:
+//	    timezone: Europe/London
+//	    unavailable:
+//	      - from: 2021-12-20
+//	        to: 2022-01-03
+//	        reason: vacation
+type Calendar struct {
+	Reviewers map[string]*Reviewer `yaml:"reviewers"`
+}
+
+// Reviewer is the availability of a single reviewer.
+type Reviewer struct {
+	// Timezone is the IANA time zone the reviewer works in. Dates are
+	// interpreted in it. Defaults to UTC.
+	Timezone string `yaml:"timezone"`
+	// Unavailable are the date ranges the reviewer is away.
+	Unavailable []Range `yaml:"unavailable"`
+
+	location *time.Location
+}
+
+// Range is an inclusive range of dates a reviewer is away.
+type Range struct {
+	From   string `yaml:"from"`
+	To     string `yaml:"to"`
+	Reason string `yaml:"reason"`
+
+	from, to time.Time
+}
+
+// Parse parses and validates a calendar.
+func Parse(data []byte) (*Calendar, error) {
+	var c Calendar
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing %v: %w", Path, err)
+	}
+	for login, r := range c.Reviewers {
+		if r == nil {
+			return nil, fmt.Errorf("%v: %v has no availability", Path, login)
+		}
+		if err := r.check(); err != nil {
+			return nil, fmt.Errorf("%v: %v: %w", Path, login, err)
+		}
+	}
+	return &c, nil
+}
+
+func (r *Reviewer) check() error {
+	location, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", r.Timezone, err)
+	}
+	r.location = location
+	for i := range r.Unavailable {
+		u := &r.Unavailable[i]
+		if u.from, err = time.Parse(dateLayout, u.From); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", u.From)
+		}
+		if u.to, err = time.Parse(dateLayout, u.To); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", u.To)
+		}
+		if u.to.Before(u.from) {
+			return fmt.Errorf("range %v to %v ends before it starts", u.From, u.To)
+		}
+	}
+	return nil
+}
+
+// Status is whether a reviewer is available on a date.
+type Status struct {
+	Reviewer  string
+	Available bool
+	// Until is the last day of the absence, if unavailable.
+	Until string
+	// Reason is the reason given for the absence, if any.
+	Reason string
+}
+
+// String describes the status for comments and command output.
+func (s Status) String() string {
+	if s.Available {
+		return "available"
+	}
+	description := "unavailable until " + s.Until
+	if s.Reason != "" {
+		description += " (" + s.Reason + ")"
+	}
+	return description
+}
+
+// At returns the status of a reviewer at an instant, using the date in the
+// reviewer's time zone. Reviewers not in the calendar are available.
+func (c *Calendar) At(login string, t time.Time) Status {
+	r := c.reviewer(login)
+	if r == nil {
+		return Status{Reviewer: login, Available: true}
+	}
+	return c.On(login, t.In(r.location).Format(dateLayout))
+}
+
+// On returns the status of a reviewer on a date in YYYY-MM-DD form.
+func (c *Calendar) On(login string, date string) Status {
+	status := Status{Reviewer: login, Available: true}
+	r := c.reviewer(login)
+	day, err := time.Parse(dateLayout, date)
+	if r == nil || err != nil {
+		return status
+	}
+	for _, u := range r.Unavailable {
+		if day.Before(u.from) || day.After(u.to) {
+			continue
+		}
+		status.Available = false
+		if u.To > status.Until {
+			status.Until = u.To
+			status.Reason = u.Reason
+		}
+	}
+	return status
+}
+
+// Logins returns the sorted logins of every reviewer in the calendar.
+func (c *Calendar) Logins() []string {
+	var logins []string
+	if c == nil {
+		return nil
+	}
+	for login := range c.Reviewers {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	return logins
+}
+
+// ParseDate validates a date in YYYY-MM-DD form.
+func ParseDate(date string) error {
+	if _, err := time.Parse(dateLayout, date); err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	return nil
+}
+
+func (c *Calendar) reviewer(login string) *Reviewer {
+	if c == nil {
+		return nil
+	}
+	return c.Reviewers[login]
+}
@@ -0,0 +1,175 @@
+// Package backport recognizes pull requests that backport another pull
+// request to a release branch and compares their changes.
+package backport
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// conventionRef matches "Backport #123" and "Backport of #123".
+	conventionRef = regexp.MustCompile(`(?i)\bbackports?\s+(?:of\s+)?#(\d+)`)
+	// plainRef matches any "#123".
+	plainRef = regexp.MustCompile(`(?:^|\s|\()#(\d+)\b`)
+)
+
+// Reference returns the number of the pull request a backport refers to.
+// The title is searched before the body. Without the backport label only
+// the "Backport #123" and "Backport of #123" conventions count; with it,
+// the first "#123" does.
+func Reference(title, body string, labeled bool) (int, bool) {
+	patterns := []*regexp.Regexp{conventionRef}
+	if labeled {
+		patterns = append(patterns, plainRef)
+	}
+	for _, re := range patterns {
+		for _, text := range []string{title, body} {
+			if m := re.FindStringSubmatch(text); m != nil {
+				number, err := strconv.Atoi(m[1])
+				if err == nil {
+					return number, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// File is a file changed by a pull request and its patch.
+type File struct {
+	// Path is the path of the file relative to the repository root.
+	Path string
+	// Previous is the path the file was renamed from, if any.
+	Previous string
+	// Status is how the file changed, e.g. added, removed or renamed.
+	Status string
+	// Patch is the unified diff of the file.
+	Patch string
+	// Blob is the object ID of the new content of the file. It is compared
+	// instead of the patch when GitHub omits the patch, e.g. for binary or
+	// very large files.
+	Blob string
+}
+
+// PatchID identifies the change to a file independently of where in the
+// file it was made, like git patch-id: hunk headers, context lines and
+// trailing whitespace are ignored. Files without a patch are identified by
+// their content.
+func PatchID(f File) string {
+	h := sha1.New()
+	h.Write([]byte(f.Path + "\n"))
+	h.Write([]byte(f.Status + " " + f.Previous + "\n"))
+	if f.Patch == "" {
+		h.Write([]byte("blob " + f.Blob + "\n"))
+	}
+	for _, line := range changedLines(f.Patch) {
+		h.Write([]byte(line + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Difference is how much a backport differs from the original.
+type Difference struct {
+	// Files are the paths whose patch IDs differ or that only one side
+	// changes.
+	Files []string
+	// Lines is the number of changed lines in differing files on both
+	// sides.
+	Lines int
+	// Total is the number of changed lines on both sides.
+	Total int
+	// Unpatched are the differing files without a patch, e.g. renames,
+	// binary files and very large files. They count as fully changed.
+	Unpatched []string
+}
+
+// Ratio is the fraction of changed lines in differing files.
+func (d Difference) Ratio() float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return float64(d.Lines) / float64(d.Total)
+}
+
+// Within returns true if at most a tolerated fraction of changed lines
+// differ. Any differing file fails a tolerance of 0, and a differing file
+// without a patch fails every tolerance, since how much of it changed is
+// unknown.
+func (d Difference) Within(tolerance float64) bool {
+	switch {
+	case len(d.Files) == 0:
+		return true
+	case tolerance == 0 || len(d.Unpatched) > 0:
+		return false
+	}
+	return d.Ratio() <= tolerance
+}
+
+// Compare compares the patch IDs of the files of an original pull request
+// and its backport.
+func Compare(original, backport []File) Difference {
+	var d Difference
+	sides := []map[string]File{index(original), index(backport)}
+	seen := make(map[string]bool)
+	for i, side := range sides {
+		other := sides[1-i]
+		for path, file := range side {
+			lines := len(changedLines(file.Patch))
+			d.Total += lines
+			match, ok := other[path]
+			if ok && PatchID(match) == PatchID(file) {
+				continue
+			}
+			d.Lines += lines
+			if !seen[path] {
+				seen[path] = true
+				d.Files = append(d.Files, path)
+			}
+			if file.Patch == "" && !contains(d.Unpatched, path) {
+				d.Unpatched = append(d.Unpatched, path)
+			}
+		}
+	}
+	sort.Strings(d.Files)
+	sort.Strings(d.Unpatched)
+	return d
+}
+
+func index(files []File) map[string]File {
+	m := make(map[string]File)
+	for _, f := range files {
+		m[f.Path] = f
+	}
+	return m
+}
+
+// changedLines returns the added and removed lines of a patch without
+// trailing whitespace. Leading whitespace is kept, since indentation is
+// significant in e.g. YAML and Python.
+func changedLines(patch string) []string {
+	var lines []string
+	for _, line := range strings.Split(patch, "\n") {
+		if !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-") {
+			continue
+		}
+		if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return lines
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
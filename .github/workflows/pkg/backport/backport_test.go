@@ -0,0 +1,151 @@
+package backport
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		title   string
+		body    string
+		labeled bool
+		want    int
+		ok      bool
+	}{
+		{name: "convention in title", title: "Backport #12 to v8", want: 12, ok: true},
+		{name: "convention in body", title: "Fix panic", body: "Backport of #34.", want: 34, ok: true},
+		{name: "plain reference without label", title: "Fix panic (#56)"},
+		{name: "plain reference with label", title: "Fix panic (#56)", labeled: true, want: 56, ok: true},
+		{name: "title before body", title: "[v8] Backport #1", body: "Backport #2", want: 1, ok: true},
+		{name: "no reference", title: "Fix panic", labeled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Reference(tt.title, tt.body, tt.labeled)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Reference() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	const patch = "@@ -1,3 +1,3 @@\n context\n-old\n+new\n"
+	tests := []struct {
+		name      string
+		original  []File
+		backport  []File
+		files     []string
+		unpatched []string
+		within    map[float64]bool
+	}{
+		{
+			name:     "identical",
+			original: []File{{Path: "a.go", Patch: patch}},
+			backport: []File{{Path: "a.go", Patch: patch}},
+			within:   map[float64]bool{0: true},
+		},
+		{
+			name:     "moved hunk",
+			original: []File{{Path: "a.go", Patch: patch}},
+			backport: []File{{Path: "a.go", Patch: "@@ -10,3 +10,3 @@\n other\n-old\n+new\n"}},
+			within:   map[float64]bool{0: true},
+		},
+		{
+			name:     "trailing whitespace",
+			original: []File{{Path: "a.go", Patch: patch}},
+			backport: []File{{Path: "a.go", Patch: "@@ -1,3 +1,3 @@\n context\n-old \n+new\t\n"}},
+			within:   map[float64]bool{0: true},
+		},
+		{
+			name:     "indentation only",
+			original: []File{{Path: "a.yml", Patch: "@@ -1 +1 @@\n-a: 1\n+b: 1\n"}},
+			backport: []File{{Path: "a.yml", Patch: "@@ -1 +1 @@\n-a: 1\n+  b: 1\n"}},
+			files:    []string{"a.yml"},
+			within:   map[float64]bool{0: false, 1: true},
+		},
+		{
+			name: "small difference",
+			original: []File{
+				{Path: "a.go", Patch: "@@ -1,9 +1,9 @@\n-1\n+1a\n-2\n+2a\n-3\n+3a\n-4\n+4a\n"},
+				{Path: "b.go", Patch: patch},
+			},
+			backport: []File{
+				{Path: "a.go", Patch: "@@ -1,9 +1,9 @@\n-1\n+1a\n-2\n+2a\n-3\n+3a\n-4\n+4a\n"},
+				{Path: "b.go", Patch: "@@ -1,3 +1,3 @@\n context\n-old\n+newer\n"},
+			},
+			files:  []string{"b.go"},
+			within: map[float64]bool{0: false, 0.1: false, 0.2: true},
+		},
+		{
+			name:     "extra file",
+			original: []File{{Path: "a.go", Patch: patch}},
+			backport: []File{{Path: "a.go", Patch: patch}, {Path: "b.go", Patch: patch}},
+			files:    []string{"b.go"},
+			within:   map[float64]bool{0: false, 0.5: true},
+		},
+		{
+			name:      "rename",
+			original:  []File{{Path: "a.go", Patch: patch}},
+			backport:  []File{{Path: "a.go", Patch: patch}, {Path: ".github/workflows/evil.yml", Previous: "b.yml", Status: "renamed", Blob: "1"}},
+			files:     []string{".github/workflows/evil.yml"},
+			unpatched: []string{".github/workflows/evil.yml"},
+			within:    map[float64]bool{0: false, 0.5: false, 1: false},
+		},
+		{
+			name:      "same rename",
+			original:  []File{{Path: "b.yml", Previous: "a.yml", Status: "renamed", Blob: "1"}},
+			backport:  []File{{Path: "b.yml", Previous: "a.yml", Status: "renamed", Blob: "1"}},
+			unpatched: nil,
+			within:    map[float64]bool{0: true},
+		},
+		{
+			name:      "rename from another file",
+			original:  []File{{Path: "b.yml", Previous: "a.yml", Status: "renamed", Blob: "1"}},
+			backport:  []File{{Path: "b.yml", Previous: "c.yml", Status: "renamed", Blob: "1"}},
+			files:     []string{"b.yml"},
+			unpatched: []string{"b.yml"},
+			within:    map[float64]bool{0: false},
+		},
+		{
+			name:      "binary",
+			original:  []File{{Path: "bin", Status: "modified", Blob: "1"}},
+			backport:  []File{{Path: "bin", Status: "modified", Blob: "2"}},
+			files:     []string{"bin"},
+			unpatched: []string{"bin"},
+			within:    map[float64]bool{0: false, 1: false},
+		},
+		{
+			name:      "added binary within tolerance of lines",
+			original:  []File{{Path: "a.go", Patch: patch}},
+			backport:  []File{{Path: "a.go", Patch: patch}, {Path: "tool", Status: "added", Blob: "1"}},
+			files:     []string{"tool"},
+			unpatched: []string{"tool"},
+			within:    map[float64]bool{0.05: false, 0.5: false, 1: false},
+		},
+		{
+			name:     "same binary",
+			original: []File{{Path: "bin", Status: "modified", Blob: "1"}},
+			backport: []File{{Path: "bin", Status: "modified", Blob: "1"}},
+			within:   map[float64]bool{0: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Compare(tt.original, tt.backport)
+			if !reflect.DeepEqual(d.Files, tt.files) {
+				t.Errorf("Files = %v, want %v", d.Files, tt.files)
+			}
+			if !reflect.DeepEqual(d.Unpatched, tt.unpatched) {
+				t.Errorf("Unpatched = %v, want %v", d.Unpatched, tt.unpatched)
+			}
+			for tolerance, want := range tt.within {
+				if got := d.Within(tolerance); got != want {
+					t.Errorf("Within(%v) = %v, want %v (%v of %v lines differ)", tolerance, got, want, d.Lines, d.Total)
+				}
+			}
+		})
+	}
+}
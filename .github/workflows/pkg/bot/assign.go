@@ -0,0 +1,426 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/availability"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/policy"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// maxReasonPaths is the number of paths listed when explaining why a code
+// owner was chosen.
+const maxReasonPaths = 5
+
+// assignmentMarker identifies the comment explaining the assignment, so
+// that it is updated in place.
+const assignmentMarker = "<!-- review-bot:assignment -->"
+
+// selection is a reviewer chosen by the bot and the reasons it was chosen.
+type selection struct {
+	// reviewer is a user login, or "org/team" for a team.
+	reviewer string
+	// team is true if reviewer is a team.
+	team bool
+	// reasons explain why the reviewer was chosen.
+	reasons []string
+}
+
+// skipped is a reviewer the bot passed over and the reason why.
+type skipped struct {
+	reviewer string
+	reason   string
+}
+
+// selections are the reviewers chosen for a pull request, in the order they
+// were chosen, and the reviewers that were passed over.
+type selections struct {
+	chosen  []*selection
+	skipped []skipped
+	// excluded are reviewers that must not be chosen and why.
+	excluded map[string]string
+}
+
+// add selects a reviewer, adding to the reasons if it was already selected.
+func (s *selections) add(reviewer string, team bool, reason string) {
+	for _, existing := range s.chosen {
+		if existing.reviewer == reviewer {
+			existing.reasons = append(existing.reasons, reason)
+			return
+		}
+	}
+	s.chosen = append(s.chosen, &selection{reviewer: reviewer, team: team, reasons: []string{reason}})
+}
+
+// skip records that a reviewer was passed over.
+func (s *selections) skip(reviewer string, reason string) {
+	for _, existing := range s.skipped {
+		if existing.reviewer == reviewer {
+			return
+		}
+	}
+	s.skipped = append(s.skipped, skipped{reviewer: reviewer, reason: reason})
+}
+
+// AssignConfig configures reviewer assignment.
+type AssignConfig struct {
+	// Number is the pull request to assign reviewers to. Defaults to the
+	// pull request in the event payload.
+	Number int
+	// Exclude maps reviewers, users or "org/team" teams, that must not be
+	// chosen to the reason why. Excluded reviewers whose review is pending
+	// do not count as already requested.
+	Exclude map[string]string
+	// ExpertiseCache is the file the commit history used to find experts is
+	// cached in between runs. Empty disables the cache.
+	ExpertiseCache string
+}
+
+// passOver returns true, and records why, if a reviewer cannot be chosen
+// because they are excluded or unavailable.
+func (s *selections) passOver(reviewer string, status availability.Status) bool {
+	if reason, ok := s.excluded[reviewer]; ok {
+		s.skip(reviewer, reason)
+		return true
+	}
+	if !status.Available {
+		s.skip(reviewer, status.String())
+		return true
+	}
+	return false
+}
+
+// Assign requests reviews on the pull request in the event payload from
+// everyone its approval policy draws on, skipping reviewers who were already
+// requested or have reviewed, and explains the choice in a comment updated
+// on every run. Drafts are not assigned reviewers.
+func (b *Bot) Assign(ctx context.Context, c AssignConfig) error {
+	_, err := b.assign(ctx, c)
+	return err
+}
+
+// assign implements Assign and returns the users and "org/team" teams it
+// requested reviews from.
+func (b *Bot) assign(ctx context.Context, c AssignConfig) ([]string, error) {
+	env := b.c.Environment
+	pr, err := b.pullRequest(ctx, c.Number)
+	if err != nil {
+		return nil, err
+	}
+	if pr.Draft {
+		if env.Action == convertedToDraft && b.c.Settings.Drafts.WithdrawReviews {
+			return nil, b.withdrawReviews(ctx, pr)
+		}
+		log.Printf("Not assigning reviewers to draft #%v.", pr.Number)
+		return nil, nil
+	}
+	classification, err := b.classify(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Assigning reviewers to #%v by %v contributor %v.", pr.Number, classification, pr.Author)
+
+	existing, err := b.existingReviewers(ctx, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+	for reviewer := range c.Exclude {
+		delete(existing, reviewer)
+	}
+	selected := selections{excluded: c.Exclude}
+	groups := b.c.Settings.GroupsFor(pr.Author)
+	update, err := b.dependencyUpdate(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if update != nil {
+		group, _ := b.c.Settings.GroupFor(update.ecosystem.Group, pr.Author)
+		groups = []config.Group{group}
+	}
+	for _, group := range groups {
+		if err := b.selectFromGroup(ctx, pr, group, b.groupReason(pr, group), existing, &selected); err != nil {
+			return nil, err
+		}
+	}
+	for _, lg := range b.c.Settings.GroupsForLabels(pr.Labels, pr.Author) {
+		reason := fmt.Sprintf("member of group `%v`, required by label `%v`", lg.Group.Name, lg.Label)
+		if err := b.selectFromGroup(ctx, pr, lg.Group, reason, existing, &selected); err != nil {
+			return nil, err
+		}
+	}
+	if err := b.selectBackportReviewers(ctx, pr, existing, &selected); err != nil {
+		return nil, err
+	}
+	if err := b.selectExperts(ctx, pr, c.ExpertiseCache, existing, &selected); err != nil {
+		return nil, err
+	}
+	if err := b.selectCodeOwners(ctx, pr, &selected); err != nil {
+		return nil, err
+	}
+	if err := b.selectForRisk(ctx, pr, existing, &selected); err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 && len(selected.chosen) == 0 {
+		return nil, fmt.Errorf("no reviewers configured for %v", pr.Author)
+	}
+
+	var users, teams, requested []string
+	for _, s := range selected.chosen {
+		// Selections that do not pass over reviewers, like code owners,
+		// can still choose excluded ones.
+		if _, excluded := c.Exclude[s.reviewer]; excluded || existing[s.reviewer] {
+			continue
+		}
+		if s.team {
+			teams = append(teams, teamSlug(s.reviewer))
+		} else {
+			users = append(users, s.reviewer)
+		}
+		requested = append(requested, s.reviewer)
+	}
+	if len(users) == 0 && len(teams) == 0 {
+		log.Printf("All selected reviewers are already assigned to #%v.", pr.Number)
+	} else {
+		log.Printf("Requesting reviews from users %v and teams %v on #%v.", users, teams, pr.Number)
+		_, _, err = b.c.GitHub.PullRequests.RequestReviewers(ctx, env.Organization, env.Repository, pr.Number, github.ReviewersRequest{
+			Reviewers:     users,
+			TeamReviewers: teams,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("requesting reviewers: %w", err)
+		}
+	}
+
+	requirements, err := b.requirements(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.stickyComment(ctx, pr.Number, assignmentMarker, explainSelections(selected, existing, requirements)); err != nil {
+		return nil, err
+	}
+	return requested, nil
+}
+
+// selectFromGroup selects reviewers from a reviewer group according to the
+// group's strategy. Excluded and unavailable reviewers are skipped; with
+// the least-loaded strategy the next least loaded reviewer is chosen in
+// their place. Teams are requested as teams with the "all" strategy unless
+// configured to request their members, and are always expanded for the
+// least-loaded strategy.
+func (b *Bot) selectFromGroup(ctx context.Context, pr *environment.Metadata, group config.Group, reason string, existing map[string]bool, selected *selections) error {
+	calendar, err := b.calendar(ctx)
+	if err != nil {
+		return err
+	}
+	if hasTeams(group.Reviewers) && (group.Strategy == config.StrategyLeastLoaded || b.c.Settings.Teams.Request == config.TeamRequestMembers) {
+		if group.Reviewers, err = b.expandTeams(ctx, group.Reviewers, pr.Author); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	if group.Strategy != config.StrategyLeastLoaded {
+		for _, reviewer := range group.Reviewers {
+			if config.IsTeam(reviewer) {
+				selected.add(strings.TrimPrefix(reviewer, "@"), true, reason)
+				continue
+			}
+			if !existing[reviewer] && selected.passOver(reviewer, calendar.At(reviewer, now)) {
+				continue
+			}
+			selected.add(reviewer, false, reason)
+		}
+		return nil
+	}
+
+	needed, ranked, err := b.rankByLoad(ctx, pr, group, existing)
+	if err != nil {
+		return err
+	}
+	var substitutes []string
+	for _, c := range ranked {
+		if needed == 0 {
+			break
+		}
+		if selected.passOver(c.reviewer, calendar.At(c.reviewer, now)) {
+			substitutes = append(substitutes, "@"+c.reviewer)
+			continue
+		}
+		why := fmt.Sprintf("%v, least loaded with %v open review requests", reason, c.load)
+		if len(substitutes) > 0 {
+			why += fmt.Sprintf(", in place of %v", strings.Join(substitutes, ", "))
+			substitutes = nil
+		}
+		selected.add(c.reviewer, false, why)
+		needed--
+	}
+	if needed > 0 {
+		log.Printf("Group %v has %v fewer available reviewers than needed.", group.Name, needed)
+	}
+	return nil
+}
+
+// groupReason explains why members of one of the author's groups are
+// chosen.
+func (b *Bot) groupReason(pr *environment.Metadata, group config.Group) string {
+	if group.Name != "" {
+		return fmt.Sprintf("member of group `%v` in `%v`", group.Name, config.Path)
+	}
+	if _, ok := b.c.Settings.Reviewers[pr.Author]; ok {
+		return fmt.Sprintf("reviewer of @%v in `%v`", pr.Author, config.Path)
+	}
+	return "default reviewer in `" + config.Path + "`"
+}
+
+// selectCodeOwners selects the smallest set of code owners covering every
+// file changed by a pull request. The author, excluded and unavailable
+// owners and owners that cannot be requested by email are not eligible.
+func (b *Bot) selectCodeOwners(ctx context.Context, pr *environment.Metadata, selected *selections) error {
+	owners, _, err := b.codeOwners(ctx)
+	if err != nil || owners == nil {
+		return err
+	}
+	files, err := b.listFiles(ctx, pr.Number)
+	if err != nil {
+		return fmt.Errorf("listing files: %w", err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.GetFilename())
+	}
+
+	calendar, err := b.calendar(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	assignments, uncovered := owners.Cover(paths, func(owner string) bool {
+		login := codeowners.Login(owner)
+		if codeowners.IsEmail(owner) || login == pr.Author {
+			return false
+		}
+		if !codeowners.IsTeam(owner) && selected.passOver(login, calendar.At(login, now)) {
+			return false
+		}
+		return true
+	})
+	for _, a := range assignments {
+		selected.add(codeowners.Login(a.Owner), codeowners.IsTeam(a.Owner), codeOwnerReason(a))
+	}
+	if len(uncovered) > 0 {
+		log.Printf("No eligible code owners for %v.", uncovered)
+	}
+	return nil
+}
+
+// codeOwners returns the CODEOWNERS file on the default branch and its
+// path, or nil if the repository does not have one.
+func (b *Bot) codeOwners(ctx context.Context) (*codeowners.File, string, error) {
+	env := b.c.Environment
+	for _, path := range codeowners.Paths {
+		data, err := config.ReadFile(ctx, b.c.GitHub, env.Organization, env.Repository, path)
+		if err != nil {
+			return nil, "", err
+		}
+		if data != nil {
+			return codeowners.Parse(data), path, nil
+		}
+	}
+	return nil, "", nil
+}
+
+// codeOwnerReason explains which files and rules made an owner a reviewer.
+func codeOwnerReason(a codeowners.Assignment) string {
+	var paths []string
+	for i, path := range a.Paths {
+		if i == maxReasonPaths {
+			paths = append(paths, fmt.Sprintf("and %v more", len(a.Paths)-maxReasonPaths))
+			break
+		}
+		paths = append(paths, "`"+path+"`")
+	}
+	var rules []string
+	for _, rule := range a.Rules {
+		rules = append(rules, fmt.Sprintf("line %v: `%v`", rule.Line, rule))
+	}
+	return fmt.Sprintf("owns %v (CODEOWNERS %v)", strings.Join(paths, ", "), strings.Join(rules, "; "))
+}
+
+// explainSelections renders the assignment comment: why each reviewer was
+// chosen, who was skipped and what approvals the check requires.
+func explainSelections(selected selections, existing map[string]bool, requirements []policy.Requirement) string {
+	var sb strings.Builder
+	sb.WriteString(assignmentMarker + "\n### Reviewers\n\n| Reviewer | Rule | Status |\n| --- | --- | --- |\n")
+	for _, s := range selected.chosen {
+		status := "requested"
+		if existing[s.reviewer] {
+			status = "already requested or reviewed"
+		}
+		fmt.Fprintf(&sb, "| @%v | %v | %v |\n", s.reviewer, strings.Join(s.reasons, "; "), status)
+	}
+	if len(selected.skipped) > 0 {
+		sb.WriteString("\nSkipped:\n\n")
+		for _, s := range selected.skipped {
+			fmt.Fprintf(&sb, "- @%v: %v\n", s.reviewer, s.reason)
+		}
+	}
+	sb.WriteString("\nRequired for `check-reviewers` to pass:\n\n")
+	for _, r := range requirements {
+		fmt.Fprintf(&sb, "- %v: %v from %v\n", r.Name, pluralize(r.Approvals, "approval"), strings.Join(r.Reviewers, ", "))
+	}
+	return sb.String()
+}
+
+// pluralize describes a count of things.
+func pluralize(n int, thing string) string {
+	if n == 1 {
+		return fmt.Sprintf("%v %v", n, thing)
+	}
+	return fmt.Sprintf("%v %vs", n, thing)
+}
+
+// teamSlug returns the slug of an "org/team" reviewer.
+func teamSlug(team string) string {
+	return team[strings.LastIndex(team, "/")+1:]
+}
+
+// existingReviewers returns the users and "org/team" teams whose review is
+// currently requested on a pull request, and the users who have already
+// reviewed it.
+func (b *Bot) existingReviewers(ctx context.Context, number int) (map[string]bool, error) {
+	env := b.c.Environment
+	existing := make(map[string]bool)
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		requested, resp, err := b.c.GitHub.PullRequests.ListReviewers(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing requested reviewers: %w", err)
+		}
+		for _, user := range requested.Users {
+			existing[user.GetLogin()] = true
+		}
+		for _, team := range requested.Teams {
+			existing[env.Organization+"/"+team.GetSlug()] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	reviews, err := b.listReviews(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("listing reviews: %w", err)
+	}
+	for _, review := range reviews {
+		existing[review.GetUser().GetLogin()] = true
+	}
+	return existing, nil
+}
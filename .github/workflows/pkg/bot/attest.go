@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/attest"
+)
+
+// AttestConfig controls which pull request is attested.
+type AttestConfig struct {
+	// Number is the pull request to attest. Defaults to the pull request in
+	// the event payload.
+	Number int
+}
+
+// Attest verifies the commits and reviews of a pull request and returns an
+// in-toto statement recording the results. An error is returned if the pull
+// request does not satisfy the policy.
+func (b *Bot) Attest(ctx context.Context, c AttestConfig) (*attest.Statement, error) {
+	env := b.c.Environment
+	pr, err := b.pullRequest(ctx, c.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := b.listCommits(ctx, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("listing commits: %w", err)
+	}
+	reviews, err := b.listReviews(ctx, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("listing reviews: %w", err)
+	}
+
+	predicate := attest.Predicate{
+		Repository:  env.Organization + "/" + env.Repository,
+		PullRequest: pr.Number,
+		HeadSHA:     pr.HeadSHA,
+		Timestamp:   time.Now().UTC(),
+	}
+	allVerified := true
+	for _, commit := range commits {
+		verification := commit.GetCommit().GetVerification()
+		predicate.Commits = append(predicate.Commits, attest.Commit{
+			SHA:      commit.GetSHA(),
+			Verified: verification.GetVerified(),
+			Reason:   verification.GetReason(),
+		})
+		allVerified = allVerified && verification.GetVerified()
+	}
+	evaluation, err := b.evaluate(ctx, pr, reviews)
+	if err != nil {
+		return nil, err
+	}
+	predicate.Approvers = evaluation.Approvers()
+	predicate.Policy = attest.Policy{
+		Name: "verified-commits-and-approvals",
+		Requirements: []attest.Requirement{{
+			Description: "all commits have verified signatures",
+			Satisfied:   allVerified,
+		}},
+	}
+	for _, result := range evaluation.Results {
+		predicate.Policy.Requirements = append(predicate.Policy.Requirements, attest.Requirement{
+			Description: result.String(),
+			Satisfied:   result.Satisfied(),
+		})
+	}
+	for _, condition := range evaluation.Conditions {
+		predicate.Policy.Requirements = append(predicate.Policy.Requirements, attest.Requirement{
+			Description: condition.Name,
+			Satisfied:   condition.Met,
+		})
+	}
+	predicate.Policy.Requirements = append(predicate.Policy.Requirements, attest.Requirement{
+		Description: "no outstanding requested changes",
+		Satisfied:   len(evaluation.ChangesRequested) == 0,
+	})
+
+	for _, r := range predicate.Policy.Requirements {
+		if !r.Satisfied {
+			return nil, fmt.Errorf("pull request %v does not satisfy policy: %v", pr.Number, r.Description)
+		}
+	}
+	return attest.NewStatement(predicate), nil
+}
@@ -0,0 +1,274 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v37/github"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+const (
+	// findingNoSigningKey is reported for members without a usable signing key.
+	findingNoSigningKey = "no-signing-key"
+	// findingExpired is reported for keys that have already expired.
+	findingExpired = "expired"
+	// findingExpiring is reported for keys expiring within the audit window.
+	findingExpiring = "expiring"
+	// findingWeakAlgorithm is reported for keys using a weak algorithm.
+	findingWeakAlgorithm = "weak-algorithm"
+	// findingUnverifiedEmail is reported for key emails not verified on the
+	// member's account.
+	findingUnverifiedEmail = "unverified-email"
+)
+
+// findingTitles are the section headings of each finding in Markdown reports.
+var findingTitles = []struct {
+	kind  string
+	title string
+}{
+	{findingNoSigningKey, "Members without a signing key"},
+	{findingExpired, "Expired keys"},
+	{findingExpiring, "Keys expiring soon"},
+	{findingWeakAlgorithm, "Keys with weak algorithms"},
+	{findingUnverifiedEmail, "Keys with unverified emails"},
+}
+
+// AuditKeysConfig controls what the key audit reports.
+type AuditKeysConfig struct {
+	// ExpiryWindow is how far ahead to report expiring keys.
+	ExpiryWindow time.Duration
+	// MinRSABits is the smallest RSA key size that is not reported as weak.
+	MinRSABits int
+}
+
+// KeyReport is the result of auditing the GPG keys of organization members.
+type KeyReport struct {
+	Organization string       `json:"organization"`
+	GeneratedAt  time.Time    `json:"generatedAt"`
+	ExpiryDays   int          `json:"expiryDays"`
+	Members      int          `json:"members"`
+	Findings     []KeyFinding `json:"findings"`
+}
+
+// KeyFinding is a single problem found with a member's keys.
+type KeyFinding struct {
+	Login  string `json:"login"`
+	KeyID  string `json:"keyId,omitempty"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// AuditKeys lists the members of the organization and reports problems with
+// the GPG keys they use to sign commits.
+func (b *Bot) AuditKeys(ctx context.Context, c AuditKeysConfig) (*KeyReport, error) {
+	org := b.c.Environment.Organization
+	members, err := b.listMembers(ctx, org)
+	if err != nil {
+		return nil, fmt.Errorf("listing members of %v: %w", org, err)
+	}
+
+	now := time.Now().UTC()
+	report := &KeyReport{
+		Organization: org,
+		GeneratedAt:  now,
+		ExpiryDays:   int(c.ExpiryWindow.Hours() / 24),
+		Members:      len(members),
+	}
+	for _, login := range members {
+		keys, err := b.listGPGKeys(ctx, login)
+		if err != nil {
+			return nil, fmt.Errorf("listing GPG keys of %v: %w", login, err)
+		}
+		report.Findings = append(report.Findings, auditMemberKeys(login, keys, now, c)...)
+	}
+	return report, nil
+}
+
+// auditMemberKeys returns the findings for the keys of a single member.
+func auditMemberKeys(login string, keys []*github.GPGKey, now time.Time, c AuditKeysConfig) []KeyFinding {
+	var findings []KeyFinding
+	canSign := false
+	for _, key := range keys {
+		keyID := key.GetKeyID()
+		expired := !key.GetExpiresAt().IsZero() && key.GetExpiresAt().Before(now)
+		switch {
+		case expired:
+			findings = append(findings, KeyFinding{
+				Login:  login,
+				KeyID:  keyID,
+				Kind:   findingExpired,
+				Detail: "expired " + key.GetExpiresAt().Format("2006-01-02"),
+			})
+		case !key.GetExpiresAt().IsZero() && key.GetExpiresAt().Before(now.Add(c.ExpiryWindow)):
+			findings = append(findings, KeyFinding{
+				Login:  login,
+				KeyID:  keyID,
+				Kind:   findingExpiring,
+				Detail: "expires " + key.GetExpiresAt().Format("2006-01-02"),
+			})
+		}
+		if !expired && signingKey(key, now) {
+			canSign = true
+		}
+
+		for _, k := range append([]*github.GPGKey{key}, key.Subkeys...) {
+			if weak, detail := weakAlgorithm(k, c.MinRSABits); weak {
+				findings = append(findings, KeyFinding{
+					Login:  login,
+					KeyID:  k.GetKeyID(),
+					Kind:   findingWeakAlgorithm,
+					Detail: detail,
+				})
+			}
+		}
+		for _, email := range key.Emails {
+			if !email.GetVerified() {
+				findings = append(findings, KeyFinding{
+					Login:  login,
+					KeyID:  keyID,
+					Kind:   findingUnverifiedEmail,
+					Detail: email.GetEmail(),
+				})
+			}
+		}
+	}
+	if !canSign {
+		findings = append(findings, KeyFinding{
+			Login:  login,
+			Kind:   findingNoSigningKey,
+			Detail: fmt.Sprintf("%v key(s), none can sign", len(keys)),
+		})
+	}
+	return findings
+}
+
+// signingKey returns true if the key or one of its unexpired subkeys can
+// sign.
+func signingKey(key *github.GPGKey, now time.Time) bool {
+	if key.GetCanSign() {
+		return true
+	}
+	for _, subkey := range key.Subkeys {
+		if subkey.GetCanSign() && (subkey.GetExpiresAt().IsZero() || subkey.GetExpiresAt().After(now)) {
+			return true
+		}
+	}
+	return false
+}
+
+// weakAlgorithm returns true and a description if the key uses DSA,
+// ElGamal or an RSA modulus shorter than minRSABits.
+func weakAlgorithm(key *github.GPGKey, minRSABits int) (bool, string) {
+	data, err := base64.StdEncoding.DecodeString(key.GetPublicKey())
+	if err != nil || len(data) == 0 {
+		return false, ""
+	}
+	op, err := packet.NewOpaqueReader(bytes.NewReader(data)).Next()
+	if err != nil || len(op.Contents) < 6 {
+		return false, ""
+	}
+	// A version 4 public key packet starts with the version, a four byte
+	// creation time and the algorithm.
+	algo := packet.PublicKeyAlgorithm(op.Contents[5])
+	switch algo {
+	case packet.PubKeyAlgoDSA:
+		return true, "DSA"
+	case packet.PubKeyAlgoElGamal:
+		return true, "ElGamal"
+	case packet.PubKeyAlgoRSA, packet.PubKeyAlgoRSAEncryptOnly, packet.PubKeyAlgoRSASignOnly:
+		p, err := op.Parse()
+		if err != nil {
+			return false, ""
+		}
+		pk, ok := p.(*packet.PublicKey)
+		if !ok {
+			return false, ""
+		}
+		bits, err := pk.BitLength()
+		if err != nil || int(bits) >= minRSABits {
+			return false, ""
+		}
+		return true, fmt.Sprintf("RSA %v bits", bits)
+	}
+	return false, ""
+}
+
+// listMembers returns the logins of every member of an organization.
+func (b *Bot) listMembers(ctx context.Context, org string) ([]string, error) {
+	var logins []string
+	opts := &github.ListMembersOptions{ListOptions: github.ListOptions{PerPage: perPage}}
+	for {
+		members, resp, err := b.c.GitHub.Organizations.ListMembers(ctx, org, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			logins = append(logins, member.GetLogin())
+		}
+		if resp.NextPage == 0 {
+			sort.Strings(logins)
+			return logins, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// listGPGKeys returns every GPG key of a user.
+func (b *Bot) listGPGKeys(ctx context.Context, login string) ([]*github.GPGKey, error) {
+	var all []*github.GPGKey
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		keys, resp, err := b.c.GitHub.Users.ListGPGKeys(ctx, login, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, keys...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *KeyReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes the report as Markdown suitable for an issue body.
+func (r *KeyReport) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## GPG key audit for %v\n\n", r.Organization)
+	fmt.Fprintf(&sb, "Audited %v members on %v, reporting keys expiring within %v days.\n",
+		r.Members, r.GeneratedAt.Format("2006-01-02"), r.ExpiryDays)
+	if len(r.Findings) == 0 {
+		sb.WriteString("\nNo problems found.\n")
+	}
+	for _, section := range findingTitles {
+		var rows []KeyFinding
+		for _, f := range r.Findings {
+			if f.Kind == section.kind {
+				rows = append(rows, f)
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n### %v\n\n| Member | Key | Detail |\n| --- | --- | --- |\n", section.title)
+		for _, f := range rows {
+			fmt.Fprintf(&sb, "| @%v | %v | %v |\n", f.Login, f.KeyID, f.Detail)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v37/github"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// rsaPublicKey returns a base64 encoded RSA public key packet of the given
+// size, as GitHub lists GPG keys.
+func rsaPublicKey(t *testing.T, bits int) string {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := packet.NewRSAPublicKey(time.Now(), &priv.PublicKey).Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// dsaPublicKey returns a base64 encoded public key packet that only holds
+// the header fields weakAlgorithm reads.
+func dsaPublicKey() string {
+	contents := []byte{4, 0, 0, 0, 0, byte(packet.PubKeyAlgoDSA)}
+	data := append([]byte{0x98, byte(len(contents))}, contents...)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestAuditMemberKeys(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	c := AuditKeysConfig{ExpiryWindow: 30 * 24 * time.Hour, MinRSABits: 2048}
+	past := now.Add(-24 * time.Hour)
+	soon := now.Add(7 * 24 * time.Hour)
+	later := now.Add(365 * 24 * time.Hour)
+	strong := rsaPublicKey(t, 2048)
+	short := rsaPublicKey(t, 1024)
+
+	tests := []struct {
+		name  string
+		keys  []*github.GPGKey
+		kinds []string
+	}{
+		{
+			name:  "no keys",
+			kinds: []string{findingNoSigningKey},
+		},
+		{
+			name: "valid signing key",
+			keys: []*github.GPGKey{{KeyID: github.String("A"), PublicKey: &strong, CanSign: github.Bool(true), ExpiresAt: &later}},
+		},
+		{
+			name:  "expired key",
+			keys:  []*github.GPGKey{{KeyID: github.String("A"), PublicKey: &strong, CanSign: github.Bool(true), ExpiresAt: &past}},
+			kinds: []string{findingExpired, findingNoSigningKey},
+		},
+		{
+			name:  "expiring key",
+			keys:  []*github.GPGKey{{KeyID: github.String("A"), PublicKey: &strong, CanSign: github.Bool(true), ExpiresAt: &soon}},
+			kinds: []string{findingExpiring},
+		},
+		{
+			name: "valid signing subkey",
+			keys: []*github.GPGKey{{
+				KeyID:     github.String("A"),
+				PublicKey: &strong,
+				Subkeys:   []*github.GPGKey{{KeyID: github.String("B"), PublicKey: &strong, CanSign: github.Bool(true), ExpiresAt: &later}},
+			}},
+		},
+		{
+			name: "expired signing subkey",
+			keys: []*github.GPGKey{{
+				KeyID:     github.String("A"),
+				PublicKey: &strong,
+				Subkeys:   []*github.GPGKey{{KeyID: github.String("B"), PublicKey: &strong, CanSign: github.Bool(true), ExpiresAt: &past}},
+			}},
+			kinds: []string{findingNoSigningKey},
+		},
+		{
+			name:  "DSA key",
+			keys:  []*github.GPGKey{{KeyID: github.String("A"), PublicKey: github.String(dsaPublicKey()), CanSign: github.Bool(true)}},
+			kinds: []string{findingWeakAlgorithm},
+		},
+		{
+			name:  "short RSA subkey",
+			keys:  []*github.GPGKey{{KeyID: github.String("A"), PublicKey: &strong, CanSign: github.Bool(true), Subkeys: []*github.GPGKey{{KeyID: github.String("B"), PublicKey: &short}}}},
+			kinds: []string{findingWeakAlgorithm},
+		},
+		{
+			name: "unverified email",
+			keys: []*github.GPGKey{{
+				KeyID:     github.String("A"),
+				PublicKey: &strong,
+				CanSign:   github.Bool(true),
+				Emails:    []*github.GPGEmail{{Email: github.String("alice@example.com"), Verified: github.Bool(false)}},
+			}},
+			kinds: []string{findingUnverifiedEmail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds []string
+			for _, finding := range auditMemberKeys("alice", tt.keys, now, c) {
+				kinds = append(kinds, finding.Kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("findings = %v, want %v", kinds, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestWeakAlgorithm(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicKey string
+		weak      bool
+		detail    string
+	}{
+		{name: "DSA", publicKey: dsaPublicKey(), weak: true, detail: "DSA"},
+		{name: "short RSA", publicKey: rsaPublicKey(t, 1024), weak: true, detail: "RSA 1024 bits"},
+		{name: "RSA", publicKey: rsaPublicKey(t, 2048)},
+		{name: "malformed", publicKey: "not base64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weak, detail := weakAlgorithm(&github.GPGKey{PublicKey: github.String(tt.publicKey)}, 2048)
+			if weak != tt.weak || detail != tt.detail {
+				t.Errorf("weakAlgorithm() = %v, %q, want %v, %q", weak, detail, tt.weak, tt.detail)
+			}
+		})
+	}
+}
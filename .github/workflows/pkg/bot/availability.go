@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"context"
+	"sort"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/availability"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+)
+
+// calendar returns the reviewer availability calendar on the default
+// branch. It returns nil, which treats everybody as available, if the
+// repository does not have one. The result is cached for the lifetime of
+// the bot.
+func (b *Bot) calendar(ctx context.Context) (*availability.Calendar, error) {
+	if b.calendarLoaded {
+		return b.cal, nil
+	}
+	env := b.c.Environment
+	data, err := config.ReadFile(ctx, b.c.GitHub, env.Organization, env.Repository, availability.Path)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if b.cal, err = availability.Parse(data); err != nil {
+			return nil, err
+		}
+	}
+	b.calendarLoaded = true
+	return b.cal, nil
+}
+
+// Availability returns the availability on a date, in YYYY-MM-DD form, of
+// every configured reviewer, including team members, and everybody in the
+// calendar.
+func (b *Bot) Availability(ctx context.Context, date string) ([]availability.Status, error) {
+	if err := availability.ParseDate(date); err != nil {
+		return nil, err
+	}
+	calendar, err := b.calendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reviewers, err := b.expandTeams(ctx, b.c.Settings.AllReviewers(), "")
+	if err != nil {
+		return nil, err
+	}
+	logins := make(map[string]bool)
+	for _, login := range reviewers {
+		logins[login] = true
+	}
+	for _, login := range calendar.Logins() {
+		logins[login] = true
+	}
+
+	var statuses []availability.Status
+	for login := range logins {
+		statuses = append(statuses, calendar.On(login, date))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Reviewer < statuses[j].Reviewer })
+	return statuses, nil
+}
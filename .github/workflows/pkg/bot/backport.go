@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/backport"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+)
+
+// maxCompareFiles is the most files GitHub lists when comparing two commits.
+const maxCompareFiles = 300
+
+// backportInfo describes the original pull request of a backport.
+type backportInfo struct {
+	// number is the number of the original pull request.
+	number int
+	// approvers approved the original pull request.
+	approvers []string
+	// difference is how much the backport's changes differ from the
+	// original's.
+	difference backport.Difference
+	// matches is true if the difference is within the configured
+	// tolerance.
+	matches bool
+}
+
+// backportOf returns the original of a backport, or nil if the pull request
+// is not a backport of a merged pull request. Results are cached for the
+// lifetime of the bot.
+func (b *Bot) backportOf(ctx context.Context, pr *environment.Metadata) (*backportInfo, error) {
+	if info, ok := b.backports[pr.Number]; ok {
+		return info, nil
+	}
+	info, err := b.findBackport(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if b.backports == nil {
+		b.backports = make(map[int]*backportInfo)
+	}
+	b.backports[pr.Number] = info
+	return info, nil
+}
+
+// findBackport finds and compares the original of a backport.
+func (b *Bot) findBackport(ctx context.Context, pr *environment.Metadata) (*backportInfo, error) {
+	env := b.c.Environment
+	settings := b.c.Settings.Backports
+	if !settings.Branch(pr.BaseRef) {
+		return nil, nil
+	}
+	labeled := contains(pr.Labels, settings.Label)
+	number, ok := backport.Reference(pr.Title, pr.Body, labeled)
+	if !ok {
+		if labeled {
+			log.Printf("#%v is labeled %v but does not refer to the original pull request.", pr.Number, settings.Label)
+		}
+		return nil, nil
+	}
+	original, _, err := b.c.GitHub.PullRequests.Get(ctx, env.Organization, env.Repository, number)
+	if err != nil {
+		return nil, fmt.Errorf("fetching original pull request %v: %w", number, err)
+	}
+	if !original.GetMerged() {
+		log.Printf("#%v refers to #%v, which is not merged.", pr.Number, number)
+		return nil, nil
+	}
+
+	reviews, err := b.listReviews(ctx, number)
+	if err != nil {
+		return nil, fmt.Errorf("listing reviews of #%v: %w", number, err)
+	}
+	originalFiles, originalComplete, err := b.compareFiles(ctx, original.GetBase().GetSHA(), original.GetHead().GetSHA())
+	if err != nil {
+		return nil, err
+	}
+	backportFiles, backportComplete, err := b.compareFiles(ctx, pr.BaseRef, pr.HeadSHA)
+	if err != nil {
+		return nil, err
+	}
+	difference := backport.Compare(originalFiles, backportFiles)
+	info := &backportInfo{
+		number:     number,
+		approvers:  approvers(latestReviews(reviews)),
+		difference: difference,
+		matches:    difference.Within(settings.Tolerance),
+	}
+	if !originalComplete || !backportComplete {
+		// Files past the cap are not listed, so they cannot be compared.
+		log.Printf("#%v cannot match #%v: too many changed files to compare.", pr.Number, number)
+		info.matches = false
+	}
+	if len(difference.Files) > 0 {
+		log.Printf("#%v differs from #%v in %.0f%% of changed lines, in %v.", pr.Number, number, difference.Ratio()*100, difference.Files)
+	}
+	if len(difference.Unpatched) > 0 {
+		log.Printf("#%v cannot match #%v: %v differ without a patch to compare.", pr.Number, number, difference.Unpatched)
+	}
+	return info, nil
+}
+
+// compareFiles returns the patches of the files changed between two
+// commits. The comparison lists at most maxCompareFiles files, so it
+// returns false if the list may be incomplete.
+func (b *Bot) compareFiles(ctx context.Context, base, head string) ([]backport.File, bool, error) {
+	env := b.c.Environment
+	comparison, _, err := b.c.GitHub.Repositories.CompareCommits(ctx, env.Organization, env.Repository, base, head)
+	if err != nil {
+		return nil, false, fmt.Errorf("comparing %v with %v: %w", head, base, err)
+	}
+	var files []backport.File
+	for _, file := range comparison.Files {
+		files = append(files, backport.File{
+			Path:     file.GetFilename(),
+			Previous: file.GetPreviousFilename(),
+			Status:   file.GetStatus(),
+			Patch:    file.GetPatch(),
+			Blob:     file.GetSHA(),
+		})
+	}
+	return files, len(comparison.Files) < maxCompareFiles, nil
+}
+
+// selectBackportReviewers requests reviews on a backport from the people
+// who approved the original pull request.
+func (b *Bot) selectBackportReviewers(ctx context.Context, pr *environment.Metadata, existing map[string]bool, selected *selections) error {
+	info, err := b.backportOf(ctx, pr)
+	if err != nil || info == nil {
+		return err
+	}
+	calendar, err := b.calendar(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, approver := range info.approvers {
+		if approver == pr.Author {
+			continue
+		}
+		if !existing[approver] && selected.passOver(approver, calendar.At(approver, now)) {
+			continue
+		}
+		selected.add(approver, false, fmt.Sprintf("approved the original pull request #%v", info.number))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package bot implements the review bot's subcommands.
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/availability"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/contributor"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/risk"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// Config holds the dependencies of the bot.
+type Config struct {
+	// GitHub is the API client.
+	GitHub *github.Client
+	// Environment is the context the bot is running in.
+	Environment *environment.Environment
+	// Settings is the review bot configuration.
+	Settings *config.Config
+}
+
+// CheckAndSetDefaults validates the configuration.
+func (c *Config) CheckAndSetDefaults() error {
+	if c.GitHub == nil {
+		return fmt.Errorf("missing GitHub client")
+	}
+	if c.Environment == nil {
+		return fmt.Errorf("missing environment")
+	}
+	if c.Settings == nil {
+		return fmt.Errorf("missing settings")
+	}
+	return nil
+}
+
+// Bot performs actions on pull requests.
+type Bot struct {
+	c          *Config
+	classifier *contributor.Classifier
+	// requests caches the users whose review is requested on each open
+	// pull request.
+	requests map[int][]string
+	// cal caches the reviewer availability calendar once calendarLoaded
+	// is set.
+	cal            *availability.Calendar
+	calendarLoaded bool
+	// teams caches the members of "@org/team" entries.
+	teams map[string][]string
+	// scores caches the risk score of pull requests.
+	scores map[int]risk.Score
+	// members caches organization membership.
+	members map[string]bool
+	// backports caches the originals of backports, nil if a pull
+	// request is not one.
+	backports map[int]*backportInfo
+	// updates caches the dependency updates pull requests are, nil if a
+	// pull request is not one.
+	updates map[int]*dependencyUpdate
+	// twoPersonRules caches the two-person rule of pull requests, nil if
+	// it does not apply.
+	twoPersonRules map[int]*twoPersonRule
+	// pushTimes caches when pull requests were last pushed.
+	pushTimes map[int]time.Time
+}
+
+// New returns a bot for the given configuration.
+func New(c *Config) (*Bot, error) {
+	if err := c.CheckAndSetDefaults(); err != nil {
+		return nil, err
+	}
+	return &Bot{
+		c: c,
+		classifier: contributor.NewClassifier(contributor.Config{
+			GitHub:       c.GitHub,
+			Organization: c.Environment.Organization,
+			Repository:   c.Environment.Repository,
+			Bots:         c.Settings.Bots,
+		}),
+	}, nil
+}
+
+// classify returns the kind of contributor the author of a pull request is.
+func (b *Bot) classify(ctx context.Context, pr *environment.Metadata) (contributor.Classification, error) {
+	return b.classifier.Classify(ctx, pr.Author, pr.AuthorType, pr.AuthorAssociation)
+}
+
+// pullRequest returns the pull request the bot is acting on: the one in the
+// event payload, or the given number if the event does not refer to one.
+func (b *Bot) pullRequest(ctx context.Context, number int) (*environment.Metadata, error) {
+	env := b.c.Environment
+	if number == 0 {
+		if env.Metadata == nil {
+			return nil, fmt.Errorf("%v event does not refer to a pull request", env.EventName)
+		}
+		return env.Metadata, nil
+	}
+	pr, _, err := b.c.GitHub.PullRequests.Get(ctx, env.Organization, env.Repository, number)
+	if err != nil {
+		return nil, fmt.Errorf("fetching pull request %v: %w", number, err)
+	}
+	return environment.NewMetadata(pr), nil
+}
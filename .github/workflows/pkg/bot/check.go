@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/policy"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// Check evaluates the reviews of the pull request in the event payload
+// against the approval policy and returns an error explaining what is
+// missing if it is not approved. Drafts get a neutral check run instead,
+// which reports the outcome once they are ready for review, a /skip-review
+// of the head commit approves it without reviews, and qualifying
+// dependency updates are approved by the bot.
+func (b *Bot) Check(ctx context.Context) error {
+	pr, err := b.pullRequest(ctx, 0)
+	if err != nil {
+		return err
+	}
+	if pr.Draft {
+		log.Printf("#%v is a draft, not checking reviews.", pr.Number)
+		return b.reportDraft(ctx, pr)
+	}
+	err = b.checkReviews(ctx, pr)
+	if reportErr := b.reportReady(ctx, pr, err); reportErr != nil {
+		return reportErr
+	}
+	return err
+}
+
+// checkReviews checks the reviews of a pull request that is ready for
+// review.
+func (b *Bot) checkReviews(ctx context.Context, pr *environment.Metadata) error {
+	skipped, err := b.skippedReview(ctx, pr)
+	if err != nil {
+		return err
+	}
+	if skipped != nil {
+		log.Printf("Review of #%v at %v was skipped by %v: %v.", pr.Number, shortSHA(pr.HeadSHA), skipped.Actor, skipped.Result)
+		return nil
+	}
+	reviews, err := b.listReviews(ctx, pr.Number)
+	if err != nil {
+		return fmt.Errorf("listing reviews: %w", err)
+	}
+	dismissed, err := b.invalidateApprovals(ctx, pr, reviews)
+	if err != nil {
+		return err
+	}
+	if dismissed {
+		if reviews, err = b.listReviews(ctx, pr.Number); err != nil {
+			return fmt.Errorf("listing reviews: %w", err)
+		}
+	}
+	evaluation, err := b.evaluate(ctx, pr, reviews)
+	if err != nil {
+		return err
+	}
+
+	classification, err := b.classify(ctx, pr)
+	if err != nil {
+		return err
+	}
+	log.Printf("Checking reviews of #%v by %v contributor %v.", pr.Number, classification, pr.Author)
+	score, err := b.riskScore(ctx, pr.Number)
+	if err != nil {
+		return err
+	}
+	explanation := fmt.Sprintf("%v\n%v", score, evaluation.Explain())
+	expiry, err := b.approvalExpiry(ctx, pr)
+	if err != nil {
+		return err
+	}
+	if expiry != nil {
+		explanation = fmt.Sprintf("%v\n%v", explanation, expiry)
+	}
+	if !evaluation.Approved() {
+		return fmt.Errorf("#%v is not approved:\n%v", pr.Number, explanation)
+	}
+	log.Printf("#%v is approved:\n%v", pr.Number, explanation)
+	return b.approveUpdate(ctx, pr, reviews)
+}
+
+// evaluate evaluates the latest reviews of a pull request against the
+// requirements of the approval policy. Approvals given more than the
+// maximum age for the base branch before the latest push have expired and
+// do not count. Requested changes block approval if the reviewer is
+// eligible or has write access. Dependency updates must also pass their
+// ecosystem's checks, and the co-authors of changes to sensitive paths must
+// be known.
+func (b *Bot) evaluate(ctx context.Context, pr *environment.Metadata, reviews []*github.PullRequestReview) (policy.Evaluation, error) {
+	requirements, err := b.requirements(ctx, pr)
+	if err != nil {
+		return policy.Evaluation{}, err
+	}
+	expiry, err := b.approvalExpiry(ctx, pr)
+	if err != nil {
+		return policy.Evaluation{}, err
+	}
+	eligible := make(map[string]bool)
+	for _, requirement := range requirements {
+		for _, reviewer := range requirement.Reviewers {
+			eligible[reviewer] = true
+		}
+	}
+	var latest []policy.Review
+	for login, review := range latestReviews(reviews) {
+		// Write access only matters for reviewers whose requested changes
+		// would not block approval anyway.
+		writer := false
+		if review.GetState() == policy.ChangesRequested && !eligible[login] {
+			permission, err := b.permission(ctx, login)
+			if err != nil {
+				return policy.Evaluation{}, err
+			}
+			writer = hasPermission(permission, permissionWrite)
+		}
+		latest = append(latest, policy.Review{
+			Author:  login,
+			State:   review.GetState(),
+			Expired: expiry != nil && expiry.expired(review.GetSubmittedAt()),
+			Writer:  writer,
+		})
+	}
+	sort.Slice(latest, func(i, j int) bool { return latest[i].Author < latest[j].Author })
+	evaluation := policy.Evaluate(requirements, latest)
+
+	update, err := b.dependencyUpdate(ctx, pr)
+	if err != nil {
+		return policy.Evaluation{}, err
+	}
+	if update != nil {
+		conditions, err := b.checkConditions(ctx, pr, update.ecosystem)
+		if err != nil {
+			return policy.Evaluation{}, err
+		}
+		evaluation.Conditions = append(evaluation.Conditions, conditions...)
+	}
+	rule, err := b.twoPerson(ctx, pr)
+	if err != nil {
+		return policy.Evaluation{}, err
+	}
+	if rule != nil {
+		evaluation.Conditions = append(evaluation.Conditions, twoPersonCondition(rule))
+	}
+	return evaluation, nil
+}
+
+// requirements returns the approvals a pull request needs: approval from
+// the author's reviewers, or the rotation of a dependency update, and from
+// each group pulled in by a label, and the two-person rule for sensitive
+// paths. Teams are expanded so that an approval from any member counts for
+// the team.
+func (b *Bot) requirements(ctx context.Context, pr *environment.Metadata) ([]policy.Requirement, error) {
+	update, err := b.dependencyUpdate(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	var first policy.Requirement
+	if update != nil {
+		first, err = b.updateRequirement(ctx, pr, update)
+	} else {
+		first, err = b.authorRequirement(ctx, pr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	requirements := []policy.Requirement{first}
+	for _, lg := range b.c.Settings.GroupsForLabels(pr.Labels, pr.Author) {
+		members, err := b.expandTeams(ctx, lg.Group.Reviewers, pr.Author)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, policy.Requirement{
+			Name:      fmt.Sprintf("approval from group %v for label %v", lg.Group.Name, lg.Label),
+			Reviewers: members,
+			Approvals: 1,
+		})
+	}
+	rule, err := b.twoPerson(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		requirement, err := b.twoPersonRequirement(ctx, pr, rule)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, requirement)
+	}
+	return requirements, nil
+}
+
+// authorRequirement returns the approvals needed from the author's
+// reviewers. The pull request's risk tier can raise the number of
+// approvals, and a backport whose changes match the original's needs
+// fewer, which the original's approvers can also give.
+func (b *Bot) authorRequirement(ctx context.Context, pr *environment.Metadata) (policy.Requirement, error) {
+	reviewers, err := b.expandTeams(ctx, b.c.Settings.ReviewersFor(pr.Author), pr.Author)
+	if err != nil {
+		return policy.Requirement{}, err
+	}
+	approvals, _, err := b.requiredApprovals(ctx, pr.Number)
+	if err != nil {
+		return policy.Requirement{}, err
+	}
+	name := "approval from the author's reviewers"
+	info, err := b.backportOf(ctx, pr)
+	if err != nil {
+		return policy.Requirement{}, err
+	}
+	if info != nil && info.matches {
+		name = fmt.Sprintf("approval from the author's reviewers or the approvers of #%v, reduced for a matching backport", info.number)
+		for _, approver := range info.approvers {
+			if approver != pr.Author && !contains(reviewers, approver) {
+				reviewers = append(reviewers, approver)
+			}
+		}
+		if reduced := b.c.Settings.Backports.Approvals; reduced < approvals {
+			approvals = reduced
+		}
+	}
+	return policy.Requirement{
+		Name:      name,
+		Reviewers: reviewers,
+		Approvals: approvals,
+	}, nil
+}
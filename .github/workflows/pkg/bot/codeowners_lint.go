@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+)
+
+// CodeOwnersReport is the result of linting a CODEOWNERS file.
+type CodeOwnersReport struct {
+	// Path is the location of the CODEOWNERS file.
+	Path string
+	// Problems are the problems found in the file.
+	Problems []CodeOwnersProblem
+	// Files is the number of files in the repository.
+	Files int
+	// Unowned are the files in the repository without an owner.
+	Unowned []string
+	// Truncated is true if GitHub did not return every file.
+	Truncated bool
+}
+
+// CodeOwnersProblem is a problem with a single CODEOWNERS line.
+type CodeOwnersProblem struct {
+	Line    int
+	Message string
+}
+
+// LintCodeOwners checks a CODEOWNERS file for lines without owners,
+// invalid patterns, unknown users and teams, and owners without write
+// access, and reports the files on the default branch without an owner. If
+// file is nil the CODEOWNERS file on the default branch is linted.
+func (b *Bot) LintCodeOwners(ctx context.Context, file *codeowners.File, path string) (*CodeOwnersReport, error) {
+	env := b.c.Environment
+	if file == nil {
+		var err error
+		if file, path, err = b.codeOwners(ctx); err != nil {
+			return nil, err
+		}
+		if file == nil {
+			return nil, fmt.Errorf("no CODEOWNERS file on the default branch")
+		}
+	}
+
+	report := &CodeOwnersReport{Path: path}
+	// Problems with each owner, checked once per owner.
+	checked := make(map[string]string)
+	for _, rule := range file.Rules {
+		if rule.Err != nil {
+			report.problem(rule, rule.Err.Error())
+			continue
+		}
+		if len(rule.Owners) == 0 {
+			report.problem(rule, fmt.Sprintf("`%v` has no owners, matching files are unowned", rule.Pattern))
+		}
+		for _, owner := range rule.Owners {
+			problem, ok := checked[owner]
+			if !ok {
+				var err error
+				if problem, err = b.checkOwner(ctx, owner); err != nil {
+					return nil, err
+				}
+				checked[owner] = problem
+			}
+			if problem != "" {
+				report.problem(rule, problem)
+			}
+		}
+	}
+
+	repository, _, err := b.c.GitHub.Repositories.Get(ctx, env.Organization, env.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repository: %w", err)
+	}
+	tree, _, err := b.c.GitHub.Git.GetTree(ctx, env.Organization, env.Repository, repository.GetDefaultBranch(), true)
+	if err != nil {
+		return nil, fmt.Errorf("fetching tree of %v: %w", repository.GetDefaultBranch(), err)
+	}
+	report.Truncated = tree.GetTruncated()
+	for _, entry := range tree.Entries {
+		if entry.GetType() != "blob" {
+			continue
+		}
+		report.Files++
+		if len(file.Owners(entry.GetPath())) == 0 {
+			report.Unowned = append(report.Unowned, entry.GetPath())
+		}
+	}
+	return report, nil
+}
+
+// checkOwner returns a description of the problem with an owner, or an
+// empty string if the owner exists and has write access.
+func (b *Bot) checkOwner(ctx context.Context, owner string) (string, error) {
+	env := b.c.Environment
+	if err := codeowners.ValidOwner(owner); err != nil {
+		return err.Error(), nil
+	}
+	switch {
+	case codeowners.IsEmail(owner):
+		// Emails cannot be resolved to accounts through the API.
+		return "", nil
+	case codeowners.IsTeam(owner):
+		parts := strings.SplitN(codeowners.Login(owner), "/", 2)
+		if parts[0] != env.Organization {
+			return fmt.Sprintf("team %v is not in the %v organization", owner, env.Organization), nil
+		}
+		_, resp, err := b.c.GitHub.Teams.GetTeamBySlug(ctx, parts[0], parts[1])
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return fmt.Sprintf("unknown team %v", owner), nil
+			}
+			return "", fmt.Errorf("fetching team %v: %w", owner, err)
+		}
+		repo, resp, err := b.c.GitHub.Teams.IsTeamRepoBySlug(ctx, parts[0], parts[1], env.Organization, env.Repository)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return fmt.Sprintf("team %v has no access to the repository", owner), nil
+			}
+			return "", fmt.Errorf("checking access of team %v: %w", owner, err)
+		}
+		if !repo.GetPermissions()["push"] {
+			return fmt.Sprintf("team %v does not have write access", owner), nil
+		}
+		return "", nil
+	default:
+		login := codeowners.Login(owner)
+		_, resp, err := b.c.GitHub.Users.Get(ctx, login)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return fmt.Sprintf("unknown user %v", owner), nil
+			}
+			return "", fmt.Errorf("fetching user %v: %w", owner, err)
+		}
+		level, _, err := b.c.GitHub.Repositories.GetPermissionLevel(ctx, env.Organization, env.Repository, login)
+		if err != nil {
+			return "", fmt.Errorf("checking permission of %v: %w", owner, err)
+		}
+		switch level.GetPermission() {
+		case "admin", "write":
+			return "", nil
+		}
+		return fmt.Sprintf("user %v does not have write access", owner), nil
+	}
+}
+
+func (r *CodeOwnersReport) problem(rule *codeowners.Rule, message string) {
+	r.Problems = append(r.Problems, CodeOwnersProblem{Line: rule.Line, Message: message})
+}
+
+// WriteMarkdown writes the report as Markdown.
+func (r *CodeOwnersReport) WriteMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %v\n\n", r.Path)
+	if len(r.Problems) == 0 {
+		sb.WriteString("No problems found.\n")
+	} else {
+		sb.WriteString("| Line | Problem |\n| --- | --- |\n")
+		for _, p := range r.Problems {
+			fmt.Fprintf(&sb, "| %v | %v |\n", p.Line, p.Message)
+		}
+	}
+
+	owned := r.Files - len(r.Unowned)
+	fmt.Fprintf(&sb, "\n### Coverage\n\n%v of %v files have an owner", owned, r.Files)
+	if r.Files > 0 {
+		fmt.Fprintf(&sb, " (%.1f%%)", 100*float64(owned)/float64(r.Files))
+	}
+	sb.WriteString(".\n")
+	if r.Truncated {
+		sb.WriteString("\nThe repository tree was truncated by GitHub, some files were not checked.\n")
+	}
+	if len(r.Unowned) > 0 {
+		sb.WriteString("\nFiles without an owner:\n\n")
+		for _, path := range r.Unowned {
+			fmt.Fprintf(&sb, "- `%v`\n", path)
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
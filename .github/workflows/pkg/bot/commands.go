@@ -0,0 +1,417 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/commands"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+
+	"github.com/google/go-github/v37/github"
+)
+
+const (
+	// permissionAdmin is the repository permission of administrators.
+	permissionAdmin = "admin"
+	// permissionWrite is the repository permission of people who can push.
+	permissionWrite = "write"
+)
+
+// auditMarker matches the hidden audit record the bot leaves for every
+// command it handles.
+var auditMarker = regexp.MustCompile(`<!-- review-bot:audit (\{.*\}) -->`)
+
+// CommentConfig configures comment command handling.
+type CommentConfig struct {
+	// CheckWorkflow is the file name of the workflow /recheck re-runs.
+	CheckWorkflow string
+}
+
+// auditRecord is an entry in the audit trail of commands. Records are
+// written to the log and kept in a hidden marker in the bot's reply.
+type auditRecord struct {
+	Time        time.Time `json:"time"`
+	Actor       string    `json:"actor"`
+	Permission  string    `json:"permission"`
+	PullRequest int       `json:"pull_request"`
+	Command     string    `json:"command"`
+	Args        []string  `json:"args,omitempty"`
+	OK          bool      `json:"ok"`
+	Result      string    `json:"result"`
+	// SHA is the head commit a /skip-review applies to.
+	SHA string `json:"sha,omitempty"`
+}
+
+// marker renders the record as a hidden marker. JSON encoding escapes "<"
+// and ">", so arguments cannot end the HTML comment early or plant other
+// markers.
+func (r auditRecord) marker() (string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<!-- review-bot:audit %s -->", data), nil
+}
+
+// HandleComment runs the slash commands in the pull request comment in the
+// event payload. The commenter needs write permission, or admin permission
+// for /skip-review. The bot reacts to the comment with the outcome, replies
+// with the escaped result of each command and records each command in an
+// audit trail.
+func (b *Bot) HandleComment(ctx context.Context, c CommentConfig) error {
+	env := b.c.Environment
+	comment := env.Comment
+	if comment == nil || !comment.PullRequest || env.Action != "created" {
+		log.Printf("Ignoring %v event that is not a new pull request comment.", env.EventName)
+		return nil
+	}
+	if comment.AuthorType == "Bot" {
+		return nil
+	}
+	cmds := commands.Parse(comment.Body)
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	permission, err := b.permission(ctx, comment.Author)
+	if err != nil {
+		return err
+	}
+	pr, err := b.pullRequest(ctx, comment.Number)
+	if err != nil {
+		return err
+	}
+
+	ok := true
+	var lines []string
+	for _, cmd := range cmds {
+		record := auditRecord{
+			Time:        time.Now().UTC(),
+			Actor:       comment.Author,
+			Permission:  permission,
+			PullRequest: pr.Number,
+			Command:     cmd.Name,
+			Args:        cmd.Args,
+		}
+		result, err := b.runCommand(ctx, c, pr, cmd, &record)
+		record.OK = err == nil
+		record.Result = result
+		if err != nil {
+			ok = false
+			record.Result = err.Error()
+		}
+		marker, err := record.marker()
+		if err != nil {
+			return err
+		}
+		log.Printf("Audit: %v by %v on #%v: ok=%v %v.", cmd.Text, comment.Author, pr.Number, record.OK, record.Result)
+		lines = append(lines, fmt.Sprintf("- `/%v`: %v %v", cmd.Name, html.EscapeString(record.Result), marker))
+	}
+
+	reaction := "+1"
+	if !ok {
+		reaction = "-1"
+	}
+	if _, _, err := b.c.GitHub.Reactions.CreateIssueCommentReaction(ctx, env.Organization, env.Repository, comment.ID, reaction); err != nil {
+		return fmt.Errorf("reacting to comment %v: %w", comment.ID, err)
+	}
+	return b.comment(ctx, pr.Number, fmt.Sprintf("@%v\n\n%v\n", comment.Author, strings.Join(lines, "\n")))
+}
+
+// runCommand runs a single command and describes the result.
+func (b *Bot) runCommand(ctx context.Context, c CommentConfig, pr *environment.Metadata, cmd commands.Command, record *auditRecord) (string, error) {
+	required := permissionWrite
+	if cmd.Name == commands.SkipReview {
+		required = permissionAdmin
+	}
+	if !hasPermission(record.Permission, required) {
+		return "", fmt.Errorf("requires %v permission, @%v has %v", required, record.Actor, record.Permission)
+	}
+
+	switch cmd.Name {
+	case commands.Assign, commands.Unassign:
+		if len(cmd.Args) == 0 {
+			return "", fmt.Errorf("expected users or teams, e.g. `/%v @alice`", cmd.Name)
+		}
+		return b.changeReviewers(ctx, pr, cmd.Name == commands.Assign, cmd.Args)
+	case commands.Reroll:
+		return b.reroll(ctx, pr, record.Actor)
+	case commands.SkipReview:
+		if len(cmd.Args) == 0 {
+			return "", fmt.Errorf("expected a reason, e.g. `/skip-review revert of #123`")
+		}
+		record.SHA = pr.HeadSHA
+		if err := b.rerunCheck(ctx, pr, c.CheckWorkflow); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("review of %v skipped: %v", shortSHA(pr.HeadSHA), cmd.Reason()), nil
+	case commands.Recheck:
+		if err := b.rerunCheck(ctx, pr, c.CheckWorkflow); err != nil {
+			return "", err
+		}
+		return "re-running the approval check", nil
+	default:
+		return "", fmt.Errorf("unknown command")
+	}
+}
+
+// changeReviewers requests or removes reviews from "@user" and "@org/team"
+// arguments.
+func (b *Bot) changeReviewers(ctx context.Context, pr *environment.Metadata, add bool, args []string) (string, error) {
+	env := b.c.Environment
+	var request github.ReviewersRequest
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			return "", fmt.Errorf("%q is not a @user or @org/team", arg)
+		}
+		if !strings.Contains(arg, "/") {
+			request.Reviewers = append(request.Reviewers, strings.TrimPrefix(arg, "@"))
+			continue
+		}
+		org, slug, err := config.ParseTeam(arg)
+		if err != nil {
+			return "", err
+		}
+		if !strings.EqualFold(org, env.Organization) {
+			return "", fmt.Errorf("team %v is not in organization %v", arg, env.Organization)
+		}
+		request.TeamReviewers = append(request.TeamReviewers, slug)
+	}
+
+	if add {
+		if _, _, err := b.c.GitHub.PullRequests.RequestReviewers(ctx, env.Organization, env.Repository, pr.Number, request); err != nil {
+			return "", fmt.Errorf("requesting reviewers: %w", err)
+		}
+		return fmt.Sprintf("requested reviews from %v", strings.Join(args, ", ")), nil
+	}
+	if _, err := b.c.GitHub.PullRequests.RemoveReviewers(ctx, env.Organization, env.Repository, pr.Number, request); err != nil {
+		return "", fmt.Errorf("removing reviewers: %w", err)
+	}
+	return fmt.Sprintf("removed review requests from %v", strings.Join(args, ", ")), nil
+}
+
+// reroll replaces each pending review request, from a user or a team, with
+// other reviewers chosen the usual way. A request is only withdrawn once a
+// replacement has been requested, so requests without an alternative, e.g.
+// from a group that requests all its members, stay in place.
+func (b *Bot) reroll(ctx context.Context, pr *environment.Metadata, actor string) (string, error) {
+	env := b.c.Environment
+	pending, err := b.pendingReviewers(ctx, pr.Number)
+	if err != nil {
+		return "", err
+	}
+	if len(pending) == 0 {
+		return "", fmt.Errorf("no pending review requests to reroll")
+	}
+	// Fill any open slots first, so that reviewers requested below replace
+	// the excluded one.
+	if _, err := b.assign(ctx, AssignConfig{Number: pr.Number}); err != nil {
+		return "", err
+	}
+	reason := fmt.Sprintf("rerolled by @%v", actor)
+	exclude := make(map[string]string)
+	var replaced, kept []string
+	for _, reviewer := range pending {
+		// Earlier replaced reviewers stay excluded so they are not chosen
+		// again, while kept ones still count as requested.
+		exclude[reviewer] = reason
+		requested, err := b.assign(ctx, AssignConfig{Number: pr.Number, Exclude: exclude})
+		if err != nil {
+			return "", err
+		}
+		if len(requested) == 0 {
+			delete(exclude, reviewer)
+			kept = append(kept, "@"+reviewer)
+			continue
+		}
+		request := github.ReviewersRequest{Reviewers: []string{reviewer}}
+		if strings.Contains(reviewer, "/") {
+			request = github.ReviewersRequest{TeamReviewers: []string{teamSlug(reviewer)}}
+		}
+		if _, err := b.c.GitHub.PullRequests.RemoveReviewers(ctx, env.Organization, env.Repository, pr.Number, request); err != nil {
+			return "", fmt.Errorf("removing %v: %w", reviewer, err)
+		}
+		replaced = append(replaced, fmt.Sprintf("@%v with @%v", reviewer, strings.Join(requested, ", @")))
+	}
+	if len(replaced) == 0 {
+		return "", fmt.Errorf("no alternative reviewers to %v", strings.Join(kept, ", "))
+	}
+	result := "replaced " + strings.Join(replaced, "; ")
+	if len(kept) > 0 {
+		result += fmt.Sprintf("; kept %v without an alternative", strings.Join(kept, ", "))
+	}
+	return result, nil
+}
+
+// pendingReviewers returns the users and "org/team" teams whose review is
+// requested on a pull request.
+func (b *Bot) pendingReviewers(ctx context.Context, number int) ([]string, error) {
+	env := b.c.Environment
+	var pending []string
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		requested, resp, err := b.c.GitHub.PullRequests.ListReviewers(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing requested reviewers: %w", err)
+		}
+		for _, user := range requested.Users {
+			pending = append(pending, user.GetLogin())
+		}
+		for _, team := range requested.Teams {
+			pending = append(pending, env.Organization+"/"+team.GetSlug())
+		}
+		if resp.NextPage == 0 {
+			return pending, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// rerunCheck re-runs the latest completed run of the check workflow for the
+// head commit of a pull request.
+func (b *Bot) rerunCheck(ctx context.Context, pr *environment.Metadata, workflow string) error {
+	env := b.c.Environment
+	latest, err := b.latestCheckRun(ctx, pr, workflow)
+	if err != nil {
+		return err
+	}
+	if latest == nil {
+		return fmt.Errorf("no %v run found for %v", workflow, shortSHA(pr.HeadSHA))
+	}
+	if latest.GetStatus() != "completed" {
+		log.Printf("Run %v of %v is still %v.", latest.GetID(), workflow, latest.GetStatus())
+		return nil
+	}
+	if _, err := b.c.GitHub.Actions.RerunWorkflowByID(ctx, env.Organization, env.Repository, latest.GetID()); err != nil {
+		return fmt.Errorf("re-running run %v: %w", latest.GetID(), err)
+	}
+	return nil
+}
+
+// latestCheckRun returns the latest run of the check workflow for the head
+// of a pull request, or nil if there is none. Runs triggered by reviews are
+// listed under the pull request branch, while pull_request_target runs are
+// listed under the base branch and at its head, so they are found through
+// the pull requests they list instead.
+func (b *Bot) latestCheckRun(ctx context.Context, pr *environment.Metadata, workflow string) (*github.WorkflowRun, error) {
+	var latest *github.WorkflowRun
+	for _, source := range []struct{ event, branch string }{
+		{event: "pull_request_review", branch: pr.HeadRef},
+		{event: "pull_request_target", branch: pr.BaseRef},
+	} {
+		run, err := b.latestRunOf(ctx, pr, workflow, source.event, source.branch)
+		if err != nil {
+			return nil, err
+		}
+		if run != nil && (latest == nil || newerRun(run, latest)) {
+			latest = run
+		}
+	}
+	return latest, nil
+}
+
+// latestRunOf returns the latest run of a workflow for the head of a pull
+// request among the runs triggered by an event on a branch, or nil if there
+// is none. Runs are listed newest first, so it stops at the first page with
+// a match.
+func (b *Bot) latestRunOf(ctx context.Context, pr *environment.Metadata, workflow, event, branch string) (*github.WorkflowRun, error) {
+	env := b.c.Environment
+	opts := &github.ListWorkflowRunsOptions{
+		Branch:      branch,
+		Event:       event,
+		ListOptions: github.ListOptions{PerPage: perPage},
+	}
+	for {
+		runs, resp, err := b.c.GitHub.Actions.ListWorkflowRunsByFileName(ctx, env.Organization, env.Repository, workflow, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing %v runs of %v: %w", event, workflow, err)
+		}
+		var latest *github.WorkflowRun
+		for _, run := range runs.WorkflowRuns {
+			if runFor(run, pr) && (latest == nil || newerRun(run, latest)) {
+				latest = run
+			}
+		}
+		if latest != nil || resp.NextPage == 0 {
+			return latest, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// runFor returns true if a workflow run checked the head of a pull request.
+// A run listing pull requests must list this one at its head; others, like
+// runs for pull requests from forks, must have run at the head itself.
+func runFor(run *github.WorkflowRun, pr *environment.Metadata) bool {
+	if len(run.PullRequests) == 0 {
+		return run.GetHeadSHA() == pr.HeadSHA
+	}
+	for _, p := range run.PullRequests {
+		if p.GetNumber() == pr.Number && p.GetHead().GetSHA() == pr.HeadSHA {
+			return true
+		}
+	}
+	return false
+}
+
+// skippedReview returns the /skip-review record that applies to the head
+// commit of a pull request, or nil if its review was not skipped. Only
+// records in the bot's own comments count.
+func (b *Bot) skippedReview(ctx context.Context, pr *environment.Metadata) (*auditRecord, error) {
+	env := b.c.Environment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: perPage}}
+	for {
+		comments, resp, err := b.c.GitHub.Issues.ListComments(ctx, env.Organization, env.Repository, pr.Number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing comments: %w", err)
+		}
+		for _, comment := range comments {
+			if !isBot(comment.GetUser()) {
+				continue
+			}
+			for _, m := range auditMarker.FindAllStringSubmatch(comment.GetBody(), -1) {
+				var record auditRecord
+				if err := json.Unmarshal([]byte(m[1]), &record); err != nil {
+					continue
+				}
+				if record.OK && record.Command == commands.SkipReview && record.SHA == pr.HeadSHA {
+					return &record, nil
+				}
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// permission returns a user's permission on the repository: admin, write,
+// read or none.
+func (b *Bot) permission(ctx context.Context, login string) (string, error) {
+	env := b.c.Environment
+	level, _, err := b.c.GitHub.Repositories.GetPermissionLevel(ctx, env.Organization, env.Repository, login)
+	if err != nil {
+		return "", fmt.Errorf("fetching permission of %v: %w", login, err)
+	}
+	return level.GetPermission(), nil
+}
+
+// hasPermission returns true if a permission includes the required one.
+func hasPermission(permission, required string) bool {
+	switch required {
+	case permissionAdmin:
+		return permission == permissionAdmin
+	case permissionWrite:
+		return permission == permissionAdmin || permission == permissionWrite
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v37/github"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+)
+
+func TestRunFor(t *testing.T) {
+	pr := &environment.Metadata{Number: 7, HeadSHA: "head", HeadRef: "feature", BaseRef: "master"}
+	listing := func(number int, sha string) []*github.PullRequest {
+		return []*github.PullRequest{{
+			Number: github.Int(number),
+			Head:   &github.PullRequestBranch{SHA: github.String(sha)},
+		}}
+	}
+	tests := []struct {
+		name string
+		run  *github.WorkflowRun
+		want bool
+	}{
+		{
+			name: "review run at the head",
+			run:  &github.WorkflowRun{HeadSHA: github.String("head"), PullRequests: listing(7, "head")},
+			want: true,
+		},
+		{
+			name: "pull_request_target run at the base",
+			run:  &github.WorkflowRun{HeadSHA: github.String("base"), PullRequests: listing(7, "head")},
+			want: true,
+		},
+		{
+			name: "pull_request_target run for an earlier push",
+			run:  &github.WorkflowRun{HeadSHA: github.String("base"), PullRequests: listing(7, "old")},
+		},
+		{
+			name: "run for another pull request at the same commit",
+			run:  &github.WorkflowRun{HeadSHA: github.String("head"), PullRequests: listing(8, "head")},
+		},
+		{
+			name: "fork run at the head",
+			run:  &github.WorkflowRun{HeadSHA: github.String("head")},
+			want: true,
+		},
+		{
+			name: "fork run at the base",
+			run:  &github.WorkflowRun{HeadSHA: github.String("base")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runFor(tt.run, pr); got != tt.want {
+				t.Errorf("runFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/contributor"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/deps"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/policy"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// dependencyUpdate is a pull request by a bot that only updates the
+// dependencies of one ecosystem.
+type dependencyUpdate struct {
+	ecosystem *config.Ecosystem
+	// changes are the version changes in the ecosystem's manifests.
+	changes []deps.Change
+	// unparsed are the changed manifest lines that are not a dependency
+	// version, which rule out approving the update automatically.
+	unparsed []string
+	// autoApprove is true if the bot approves the update once its checks
+	// pass.
+	autoApprove bool
+}
+
+// describeChanges lists the version changes of an update.
+func (u *dependencyUpdate) describeChanges() string {
+	var changes []string
+	for _, c := range u.changes {
+		changes = append(changes, c.String())
+	}
+	return strings.Join(changes, ", ")
+}
+
+// dependencyUpdate returns the dependency update a pull request is, or nil
+// if it is not by a bot or changes files outside every ecosystem. Results
+// are cached for the lifetime of the bot.
+func (b *Bot) dependencyUpdate(ctx context.Context, pr *environment.Metadata) (*dependencyUpdate, error) {
+	if update, ok := b.updates[pr.Number]; ok {
+		return update, nil
+	}
+	update, err := b.findDependencyUpdate(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if b.updates == nil {
+		b.updates = make(map[int]*dependencyUpdate)
+	}
+	b.updates[pr.Number] = update
+	return update, nil
+}
+
+// findDependencyUpdate classifies a pull request as a dependency update.
+func (b *Bot) findDependencyUpdate(ctx context.Context, pr *environment.Metadata) (*dependencyUpdate, error) {
+	if len(b.c.Settings.Dependencies) == 0 {
+		return nil, nil
+	}
+	classification, err := b.classify(ctx, pr)
+	if err != nil || classification != contributor.Bot {
+		return nil, err
+	}
+	files, err := b.listFiles(ctx, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.GetFilename())
+	}
+	ecosystem := b.c.Settings.EcosystemFor(paths)
+	if ecosystem == nil {
+		log.Printf("#%v by %v changes files outside every dependency ecosystem.", pr.Number, pr.Author)
+		return nil, nil
+	}
+
+	var patches, unpatched []string
+	for _, file := range files {
+		switch {
+		case !ecosystem.Manifest(file.GetFilename()):
+		case file.GetPatch() == "":
+			unpatched = append(unpatched, file.GetFilename())
+		default:
+			patches = append(patches, file.GetPatch())
+		}
+	}
+	changes, unparsed := deps.Changes(patches)
+	for _, path := range unpatched {
+		unparsed = append(unparsed, fmt.Sprintf("%v (no diff)", path))
+	}
+	if len(unparsed) > 0 {
+		log.Printf("#%v changes manifest lines that are not dependency versions: %q.", pr.Number, unparsed)
+	}
+	update := &dependencyUpdate{
+		ecosystem: ecosystem,
+		changes:   changes,
+		unparsed:  unparsed,
+		autoApprove: ecosystem.AutoApprove == config.AutoApprovePatch &&
+			len(changes) > 0 && len(unparsed) == 0 && deps.PatchOnly(changes),
+	}
+	if update.autoApprove {
+		approvals, raised, err := b.requiredApprovals(ctx, pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		if raised {
+			log.Printf("Not approving #%v automatically: its risk tier requires %v approvals.", pr.Number, approvals)
+			update.autoApprove = false
+		}
+	}
+	return update, nil
+}
+
+// updateRequirement returns the approvals a dependency update needs from
+// its ecosystem's rotation: as many as its risk tier requires, or none if
+// the bot approves it.
+func (b *Bot) updateRequirement(ctx context.Context, pr *environment.Metadata, update *dependencyUpdate) (policy.Requirement, error) {
+	group, _ := b.c.Settings.GroupFor(update.ecosystem.Group, pr.Author)
+	reviewers, err := b.expandTeams(ctx, group.Reviewers, pr.Author)
+	if err != nil {
+		return policy.Requirement{}, err
+	}
+	approvals, _, err := b.requiredApprovals(ctx, pr.Number)
+	if err != nil {
+		return policy.Requirement{}, err
+	}
+	requirement := policy.Requirement{
+		Name:      fmt.Sprintf("approval from group %v for %v dependency updates", group.Name, update.ecosystem.Name),
+		Reviewers: reviewers,
+		Approvals: approvals,
+	}
+	if update.autoApprove {
+		requirement.Name = fmt.Sprintf("%v, waived for patch level updates (%v)", requirement.Name, update.describeChanges())
+		requirement.Approvals = 0
+	}
+	return requirement, nil
+}
+
+// checkConditions returns whether the latest run of each of an ecosystem's
+// checks by its check app completed successfully on the head commit of a
+// pull request.
+func (b *Bot) checkConditions(ctx context.Context, pr *environment.Metadata, ecosystem *config.Ecosystem) ([]policy.Condition, error) {
+	env := b.c.Environment
+	var conditions []policy.Condition
+	for _, name := range ecosystem.Checks {
+		opts := &github.ListCheckRunsOptions{
+			CheckName:   github.String(name),
+			Filter:      github.String("all"),
+			ListOptions: github.ListOptions{PerPage: perPage},
+		}
+		var runs []*github.CheckRun
+		for {
+			page, resp, err := b.c.GitHub.Checks.ListCheckRunsForRef(ctx, env.Organization, env.Repository, pr.HeadSHA, opts)
+			if err != nil {
+				return nil, fmt.Errorf("listing %v check runs: %w", name, err)
+			}
+			runs = append(runs, page.CheckRuns...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		conditions = append(conditions, policy.Condition{
+			Name: fmt.Sprintf("latest check %v by %v passed on %v", name, ecosystem.CheckApp, shortSHA(pr.HeadSHA)),
+			Met:  checkPassed(latestCheck(runs, ecosystem.CheckApp)),
+		})
+	}
+	return conditions, nil
+}
+
+// latestCheck returns the most recently created of the check runs created
+// by an app, or nil if there is none.
+func latestCheck(runs []*github.CheckRun, app string) *github.CheckRun {
+	var latest *github.CheckRun
+	for _, run := range runs {
+		if run.GetApp().GetSlug() == app && (latest == nil || run.GetID() > latest.GetID()) {
+			latest = run
+		}
+	}
+	return latest
+}
+
+// checkPassed returns true if a check run completed successfully.
+func checkPassed(run *github.CheckRun) bool {
+	return run.GetStatus() == "completed" && run.GetConclusion() == "success"
+}
+
+// approveUpdate approves the head commit of a dependency update the bot
+// approves, unless it has already approved it.
+func (b *Bot) approveUpdate(ctx context.Context, pr *environment.Metadata, reviews []*github.PullRequestReview) error {
+	env := b.c.Environment
+	update, err := b.dependencyUpdate(ctx, pr)
+	if err != nil || update == nil || !update.autoApprove {
+		return err
+	}
+	for _, review := range reviews {
+		if isBot(review.GetUser()) && review.GetState() == policy.Approved && review.GetCommitID() == pr.HeadSHA {
+			return nil
+		}
+	}
+	log.Printf("Approving patch level %v dependency update #%v.", update.ecosystem.Name, pr.Number)
+	body := fmt.Sprintf("Patch level %v dependency update approved automatically: %v.", update.ecosystem.Name, update.describeChanges())
+	event := "APPROVE"
+	_, _, err = b.c.GitHub.PullRequests.CreateReview(ctx, env.Organization, env.Repository, pr.Number, &github.PullRequestReviewRequest{
+		CommitID: &pr.HeadSHA,
+		Body:     &body,
+		Event:    &event,
+	})
+	if err != nil {
+		return fmt.Errorf("approving #%v: %w", pr.Number, err)
+	}
+	return nil
+}
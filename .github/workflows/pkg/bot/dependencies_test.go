@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v37/github"
+)
+
+func TestLatestCheck(t *testing.T) {
+	run := func(id int64, app, status, conclusion string) *github.CheckRun {
+		return &github.CheckRun{
+			ID:         github.Int64(id),
+			App:        &github.App{Slug: github.String(app)},
+			Status:     github.String(status),
+			Conclusion: github.String(conclusion),
+		}
+	}
+	tests := []struct {
+		name   string
+		runs   []*github.CheckRun
+		passed bool
+	}{
+		{name: "no runs"},
+		{
+			name:   "success",
+			runs:   []*github.CheckRun{run(1, "github-actions", "completed", "success")},
+			passed: true,
+		},
+		{
+			name: "failed re-run after success",
+			runs: []*github.CheckRun{
+				run(1, "github-actions", "completed", "success"),
+				run(2, "github-actions", "completed", "failure"),
+			},
+		},
+		{
+			name: "successful re-run after failure",
+			runs: []*github.CheckRun{
+				run(2, "github-actions", "completed", "success"),
+				run(1, "github-actions", "completed", "failure"),
+			},
+			passed: true,
+		},
+		{
+			name: "re-run in progress",
+			runs: []*github.CheckRun{
+				run(1, "github-actions", "completed", "success"),
+				run(2, "github-actions", "in_progress", ""),
+			},
+		},
+		{
+			name: "success from another app",
+			runs: []*github.CheckRun{
+				run(1, "github-actions", "completed", "failure"),
+				run(2, "impostor", "completed", "success"),
+			},
+		},
+		{
+			name: "skipped",
+			runs: []*github.CheckRun{run(1, "github-actions", "completed", "skipped")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPassed(latestCheck(tt.runs, "github-actions")); got != tt.passed {
+				t.Errorf("checkPassed(latestCheck()) = %v, want %v", got, tt.passed)
+			}
+		})
+	}
+}
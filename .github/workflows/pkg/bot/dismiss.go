@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// DismissRuns cancels queued and in-progress runs of a workflow that have
+// been superseded by a newer run for the same pull request. It is safe to
+// run concurrently with itself: cancelling a run that has already finished
+// or been cancelled is not an error.
+func (b *Bot) DismissRuns(ctx context.Context, workflow string) error {
+	runs, err := b.listActiveRuns(ctx, workflow)
+	if err != nil {
+		return fmt.Errorf("listing runs of %v: %w", workflow, err)
+	}
+
+	groups := make(map[string][]*github.WorkflowRun)
+	for _, run := range runs {
+		key := runGroup(run)
+		groups[key] = append(groups[key], run)
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		group := groups[key]
+		sort.Slice(group, func(i, j int) bool { return newerRun(group[i], group[j]) })
+		log.Printf("Keeping run %v for %v.", group[0].GetID(), key)
+		for _, run := range group[1:] {
+			if err := b.cancelRun(ctx, run); err != nil {
+				return err
+			}
+			log.Printf("Cancelled run %v for %v, superseded by run %v.", run.GetID(), key, group[0].GetID())
+		}
+	}
+	return nil
+}
+
+// listActiveRuns returns the queued and in-progress runs of a workflow.
+func (b *Bot) listActiveRuns(ctx context.Context, workflow string) ([]*github.WorkflowRun, error) {
+	env := b.c.Environment
+	var all []*github.WorkflowRun
+	seen := make(map[int64]bool)
+	for _, status := range []string{"queued", "in_progress"} {
+		opts := &github.ListWorkflowRunsOptions{
+			Status:      status,
+			ListOptions: github.ListOptions{PerPage: perPage},
+		}
+		for {
+			runs, resp, err := b.c.GitHub.Actions.ListWorkflowRunsByFileName(ctx, env.Organization, env.Repository, workflow, opts)
+			if err != nil {
+				return nil, err
+			}
+			// A run can move from queued to in progress between the two
+			// listings, so skip runs that were already seen.
+			for _, run := range runs.WorkflowRuns {
+				if !seen[run.GetID()] {
+					seen[run.GetID()] = true
+					all = append(all, run)
+				}
+			}
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+	return all, nil
+}
+
+// cancelRun cancels a workflow run, ignoring runs that have already
+// completed.
+func (b *Bot) cancelRun(ctx context.Context, run *github.WorkflowRun) error {
+	env := b.c.Environment
+	resp, err := b.c.GitHub.Actions.CancelWorkflowRunByID(ctx, env.Organization, env.Repository, run.GetID())
+	var accepted *github.AcceptedError
+	switch {
+	case err == nil, errors.As(err, &accepted):
+		return nil
+	case resp != nil && resp.StatusCode == http.StatusConflict:
+		log.Printf("Run %v has already completed.", run.GetID())
+		return nil
+	default:
+		return fmt.Errorf("cancelling run %v: %w", run.GetID(), err)
+	}
+}
+
+// runGroup returns the key of the pull request a run belongs to. Runs for
+// pull requests from forks do not list their pull request, so they are
+// grouped by head repository and branch instead.
+func runGroup(run *github.WorkflowRun) string {
+	if len(run.PullRequests) > 0 {
+		return fmt.Sprintf("#%v", run.PullRequests[0].GetNumber())
+	}
+	return fmt.Sprintf("%v:%v", run.GetHeadRepository().GetFullName(), run.GetHeadBranch())
+}
+
+// newerRun returns true if run a was created after run b.
+func newerRun(a, b *github.WorkflowRun) bool {
+	if !a.GetCreatedAt().Equal(b.GetCreatedAt()) {
+		return a.GetCreatedAt().After(b.GetCreatedAt().Time)
+	}
+	return a.GetRunNumber() > b.GetRunNumber()
+}
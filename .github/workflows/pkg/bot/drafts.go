@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+
+	"github.com/google/go-github/v37/github"
+)
+
+const (
+	// convertedToDraft is the action of a pull request event triggered
+	// when a pull request is converted back to a draft.
+	convertedToDraft = "converted_to_draft"
+	// draftCheckName is the name of the check run reporting that drafts
+	// are not checked.
+	draftCheckName = "Review approval"
+	// actionsApp is the slug of the app that creates check runs with the
+	// workflow token.
+	actionsApp = "github-actions"
+)
+
+// withdrawReviews removes every pending review request from a pull
+// request.
+func (b *Bot) withdrawReviews(ctx context.Context, pr *environment.Metadata) error {
+	env := b.c.Environment
+	requested, _, err := b.c.GitHub.PullRequests.ListReviewers(ctx, env.Organization, env.Repository, pr.Number, &github.ListOptions{PerPage: perPage})
+	if err != nil {
+		return fmt.Errorf("listing requested reviewers: %w", err)
+	}
+	var request github.ReviewersRequest
+	for _, user := range requested.Users {
+		request.Reviewers = append(request.Reviewers, user.GetLogin())
+	}
+	for _, team := range requested.Teams {
+		request.TeamReviewers = append(request.TeamReviewers, team.GetSlug())
+	}
+	if len(request.Reviewers) == 0 && len(request.TeamReviewers) == 0 {
+		return nil
+	}
+	log.Printf("Withdrawing review requests from users %v and teams %v on draft #%v.", request.Reviewers, request.TeamReviewers, pr.Number)
+	if _, err := b.c.GitHub.PullRequests.RemoveReviewers(ctx, env.Organization, env.Repository, pr.Number, request); err != nil {
+		return fmt.Errorf("removing reviewers: %w", err)
+	}
+	return nil
+}
+
+// reportDraft marks the head commit of a draft with a neutral check run,
+// since drafts are not expected to be approved yet. An earlier run of the
+// bot on the same commit is updated rather than adding another one. Tokens
+// of events from forks are read-only, so nothing is reported for those.
+func (b *Bot) reportDraft(ctx context.Context, pr *environment.Metadata) error {
+	env := b.c.Environment
+	now := github.Timestamp{Time: time.Now()}
+	output := &github.CheckRunOutput{
+		Title:   github.String("Draft"),
+		Summary: github.String(fmt.Sprintf("#%v is a draft. Reviews are checked once it is ready for review.", pr.Number)),
+	}
+	existing, err := b.draftCheckRun(ctx, pr)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return b.updateDraftCheckRun(ctx, pr, existing, "neutral", output)
+	}
+	_, resp, err := b.c.GitHub.Checks.CreateCheckRun(ctx, env.Organization, env.Repository, github.CreateCheckRunOptions{
+		Name:        draftCheckName,
+		HeadSHA:     pr.HeadSHA,
+		Status:      github.String("completed"),
+		Conclusion:  github.String("neutral"),
+		CompletedAt: &now,
+		Output:      output,
+	})
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		log.Printf("Cannot report that #%v is a draft with a read-only token: %v.", pr.Number, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reporting draft check run: %w", err)
+	}
+	return nil
+}
+
+// reportReady updates the draft check run on the head commit of a pull
+// request that is now ready for review with the outcome of checking its
+// reviews, so that it no longer says the pull request is a draft. Pull
+// requests without a draft check run are left alone.
+func (b *Bot) reportReady(ctx context.Context, pr *environment.Metadata, result error) error {
+	existing, err := b.draftCheckRun(ctx, pr)
+	if err != nil || existing == nil {
+		return err
+	}
+	conclusion := "success"
+	output := &github.CheckRunOutput{
+		Title:   github.String("Approved"),
+		Summary: github.String(fmt.Sprintf("#%v is ready for review and approved.", pr.Number)),
+	}
+	if result != nil {
+		conclusion = "failure"
+		output = &github.CheckRunOutput{
+			Title:   github.String("Not approved"),
+			Summary: github.String(result.Error()),
+		}
+	}
+	return b.updateDraftCheckRun(ctx, pr, existing, conclusion, output)
+}
+
+// updateDraftCheckRun completes an existing draft check run with a
+// conclusion. Tokens of events from forks are read-only, so it is left
+// unchanged for those.
+func (b *Bot) updateDraftCheckRun(ctx context.Context, pr *environment.Metadata, run *github.CheckRun, conclusion string, output *github.CheckRunOutput) error {
+	env := b.c.Environment
+	now := github.Timestamp{Time: time.Now()}
+	_, resp, err := b.c.GitHub.Checks.UpdateCheckRun(ctx, env.Organization, env.Repository, run.GetID(), github.UpdateCheckRunOptions{
+		Name:        draftCheckName,
+		Status:      github.String("completed"),
+		Conclusion:  github.String(conclusion),
+		CompletedAt: &now,
+		Output:      output,
+	})
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		log.Printf("Cannot update check run %v of #%v with a read-only token: %v.", run.GetID(), pr.Number, err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("updating draft check run: %w", err)
+	}
+	return nil
+}
+
+// draftCheckRun returns the bot's draft check run on the head commit of a
+// pull request, or nil if there is none.
+func (b *Bot) draftCheckRun(ctx context.Context, pr *environment.Metadata) (*github.CheckRun, error) {
+	env := b.c.Environment
+	runs, _, err := b.c.GitHub.Checks.ListCheckRunsForRef(ctx, env.Organization, env.Repository, pr.HeadSHA, &github.ListCheckRunsOptions{
+		CheckName:   github.String(draftCheckName),
+		ListOptions: github.ListOptions{PerPage: perPage},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing %v check runs: %w", draftCheckName, err)
+	}
+	for _, run := range runs.CheckRuns {
+		if run.GetApp().GetSlug() == actionsApp {
+			return run, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,261 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+
+	"github.com/google/go-github/v37/github"
+)
+
+const (
+	// stageRemind is the escalation stage in which a reviewer is reminded.
+	stageRemind = "remind"
+	// stageReassign is the escalation stage in which a reviewer is replaced.
+	stageReassign = "reassign"
+)
+
+// escalationMarker matches the hidden marker the bot leaves in each
+// escalation comment. A review request is identified by the reviewer and
+// the time it was made, so that re-requesting a review starts over.
+var escalationMarker = regexp.MustCompile(`<!-- review-bot:escalation reviewer=(\S+) stage=(\S+) requested=(\S+) -->`)
+
+// escalation is a stage of escalation of a single review request.
+type escalation struct {
+	reviewer  string
+	stage     string
+	requested string
+}
+
+// marker returns the hidden marker recording the escalation.
+func (e escalation) marker() string {
+	return fmt.Sprintf("<!-- review-bot:escalation reviewer=%v stage=%v requested=%v -->", e.reviewer, e.stage, e.requested)
+}
+
+// EscalateReviews reminds reviewers of review requests that have gone
+// unanswered for longer than the configured SLA and, after a second
+// threshold, replaces them with a backup reviewer from the same group.
+// Every escalation is recorded in a hidden marker in the bot's comment, so
+// running it repeatedly escalates each review request at most once per
+// stage.
+func (b *Bot) EscalateReviews(ctx context.Context) error {
+	prs, err := b.listOpenPullRequests(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, pr := range prs {
+		if pr.GetDraft() || len(pr.RequestedReviewers) == 0 {
+			continue
+		}
+		if err := b.escalatePullRequest(ctx, pr, now); err != nil {
+			return fmt.Errorf("escalating #%v: %w", pr.GetNumber(), err)
+		}
+	}
+	return nil
+}
+
+// escalatePullRequest escalates the overdue review requests of a pull
+// request.
+func (b *Bot) escalatePullRequest(ctx context.Context, pr *github.PullRequest, now time.Time) error {
+	settings := b.c.Settings.Escalation
+	meta := environment.NewMetadata(pr)
+	requested, err := b.reviewRequestTimes(ctx, meta.Number)
+	if err != nil {
+		return err
+	}
+	done, err := b.escalations(ctx, meta.Number)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range pr.RequestedReviewers {
+		reviewer := user.GetLogin()
+		at, ok := requested[reviewer]
+		if !ok {
+			continue
+		}
+		age := now.Sub(at)
+		key := at.UTC().Format(time.RFC3339)
+		reassign := escalation{reviewer: reviewer, stage: stageReassign, requested: key}
+		remind := escalation{reviewer: reviewer, stage: stageRemind, requested: key}
+
+		if age >= settings.Reassign && !done[reassign] {
+			reassigned, err := b.reassign(ctx, meta, reviewer, age, reassign)
+			if err != nil {
+				return err
+			}
+			if reassigned {
+				continue
+			}
+		}
+		if age >= settings.Remind && !done[remind] && !done[reassign] {
+			log.Printf("Reminding %v of #%v.", reviewer, meta.Number)
+			body := fmt.Sprintf("@%v, your review was requested %v ago. Please review or let the author know if somebody else should.\n\n%v",
+				reviewer, describeAge(age), remind.marker())
+			if err := b.comment(ctx, meta.Number, body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reassign replaces an unresponsive reviewer with a backup from the same
+// group, withdrawing the original request only once the backup has been
+// requested. It returns false if there is no backup.
+func (b *Bot) reassign(ctx context.Context, pr *environment.Metadata, reviewer string, age time.Duration, e escalation) (bool, error) {
+	env := b.c.Environment
+	existing, err := b.existingReviewers(ctx, pr.Number)
+	if err != nil {
+		return false, err
+	}
+	backup, group, err := b.backup(ctx, pr, reviewer, existing)
+	if err != nil {
+		return false, err
+	}
+	if backup == "" {
+		log.Printf("No backup for %v on #%v, reminding instead.", reviewer, pr.Number)
+		return false, nil
+	}
+
+	// Request the backup first, so that a failure never leaves the pull
+	// request without the reviewer's slot filled.
+	log.Printf("Replacing %v with %v on #%v.", reviewer, backup, pr.Number)
+	_, _, err = b.c.GitHub.PullRequests.RequestReviewers(ctx, env.Organization, env.Repository, pr.Number, github.ReviewersRequest{
+		Reviewers: []string{backup},
+	})
+	if err != nil {
+		return false, fmt.Errorf("requesting %v: %w", backup, err)
+	}
+	// The backup is already requested, so record the escalation even if
+	// the original request cannot be withdrawn, rather than requesting
+	// another backup on the next run.
+	_, err = b.c.GitHub.PullRequests.RemoveReviewers(ctx, env.Organization, env.Repository, pr.Number, github.ReviewersRequest{
+		Reviewers: []string{reviewer},
+	})
+	if err != nil {
+		log.Printf("Cannot remove %v from #%v after requesting %v: %v.", reviewer, pr.Number, backup, err)
+	}
+	body := fmt.Sprintf("@%v's review was requested %v ago without a response, so it has been reassigned to @%v from %v.\n\n%v",
+		reviewer, describeAge(age), backup, group, e.marker())
+	return true, b.comment(ctx, pr.Number, body)
+}
+
+// backup chooses an available reviewer to replace another: the least
+// loaded member of a group of the author's the reviewer belongs to, who is
+// not already reviewing the pull request. It returns an empty login if
+// there is none, and the group it was chosen from.
+func (b *Bot) backup(ctx context.Context, pr *environment.Metadata, reviewer string, existing map[string]bool) (string, string, error) {
+	calendar, err := b.calendar(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	for _, group := range b.c.Settings.GroupsFor(pr.Author) {
+		members, err := b.expandTeams(ctx, group.Reviewers, pr.Author)
+		if err != nil {
+			return "", "", err
+		}
+		if !contains(members, reviewer) {
+			continue
+		}
+		var candidates []string
+		for _, member := range members {
+			if !existing[member] {
+				candidates = append(candidates, member)
+			}
+		}
+		_, ranked, err := b.rankByLoad(ctx, pr, config.Group{Reviewers: candidates, Count: 1}, existing)
+		if err != nil {
+			return "", "", err
+		}
+		for _, c := range ranked {
+			if calendar.At(c.reviewer, now).Available {
+				name := "the author's reviewers"
+				if group.Name != "" {
+					name = fmt.Sprintf("group `%v`", group.Name)
+				}
+				return c.reviewer, name, nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// reviewRequestTimes returns when each user's review was last requested on
+// a pull request.
+func (b *Bot) reviewRequestTimes(ctx context.Context, number int) (map[string]time.Time, error) {
+	env := b.c.Environment
+	requested := make(map[string]time.Time)
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		events, resp, err := b.c.GitHub.Issues.ListIssueEvents(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing events: %w", err)
+		}
+		for _, event := range events {
+			if event.GetEvent() != "review_requested" || event.RequestedReviewer == nil {
+				continue
+			}
+			login := event.RequestedReviewer.GetLogin()
+			if at := event.GetCreatedAt(); at.After(requested[login]) {
+				requested[login] = at
+			}
+		}
+		if resp.NextPage == 0 {
+			return requested, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// escalations returns the escalations recorded in bot comments on a pull
+// request. Markers in comments by anyone but the bot are ignored so that
+// they cannot suppress escalation.
+func (b *Bot) escalations(ctx context.Context, number int) (map[escalation]bool, error) {
+	env := b.c.Environment
+	done := make(map[escalation]bool)
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: perPage}}
+	for {
+		comments, resp, err := b.c.GitHub.Issues.ListComments(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing comments: %w", err)
+		}
+		for _, comment := range comments {
+			if !isBot(comment.GetUser()) {
+				continue
+			}
+			for _, m := range escalationMarker.FindAllStringSubmatch(comment.GetBody(), -1) {
+				done[escalation{reviewer: m[1], stage: m[2], requested: m[3]}] = true
+			}
+		}
+		if resp.NextPage == 0 {
+			return done, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// describeAge describes a duration in whole hours or days.
+func describeAge(d time.Duration) string {
+	hours := int(d.Hours())
+	if hours < 48 {
+		return fmt.Sprintf("%v hours", hours)
+	}
+	return fmt.Sprintf("%v days", hours/24)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
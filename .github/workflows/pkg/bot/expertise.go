@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/expertise"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// historyMaxAge is how long the commit history of a path is cached.
+const historyMaxAge = 24 * time.Hour
+
+// selectExperts requests reviews from the author's reviewers who have
+// committed to the changed files most, weighting recent commits higher.
+// People who are no longer members of the organization are not experts.
+// The commit history of each path is kept in the cache file, if any,
+// between runs.
+func (b *Bot) selectExperts(ctx context.Context, pr *environment.Metadata, cachePath string, existing map[string]bool, selected *selections) error {
+	settings := b.c.Settings.Expertise
+	if settings.Reviewers == 0 {
+		return nil
+	}
+	pool, err := b.expandTeams(ctx, b.c.Settings.ReviewersFor(pr.Author), pr.Author)
+	if err != nil {
+		return err
+	}
+	files, err := b.listFiles(ctx, pr.Number)
+	if err != nil {
+		return fmt.Errorf("listing files: %w", err)
+	}
+
+	cache := &expertise.Cache{}
+	if cachePath != "" {
+		if cache, err = expertise.ReadCache(cachePath); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	history := make(map[string][]expertise.Commit)
+	for _, file := range files {
+		path := file.GetFilename()
+		commits, ok := cache.Get(path, now, historyMaxAge)
+		if !ok {
+			if commits, err = b.pathHistory(ctx, path, now.Add(-settings.History)); err != nil {
+				return err
+			}
+			cache.Put(path, now, commits)
+		}
+		history[path] = commits
+	}
+	if cachePath != "" {
+		cache.Prune(now, historyMaxAge)
+		if err := expertise.WriteCache(cachePath, cache); err != nil {
+			return err
+		}
+	}
+
+	eligible := make(map[string]bool)
+	for _, reviewer := range pool {
+		member, err := b.isMember(ctx, reviewer)
+		if err != nil {
+			return err
+		}
+		eligible[reviewer] = member
+	}
+	calendar, err := b.calendar(ctx)
+	if err != nil {
+		return err
+	}
+
+	needed := settings.Reviewers
+	for _, expert := range expertise.Score(history, now, func(login string) bool { return eligible[login] }) {
+		if needed == 0 {
+			break
+		}
+		if !existing[expert.Login] && selected.passOver(expert.Login, calendar.At(expert.Login, now)) {
+			continue
+		}
+		selected.add(expert.Login, false, fmt.Sprintf("expert in the changed files with a recent commit score of %.1f", expert.Score))
+		needed--
+	}
+	if needed > 0 {
+		log.Printf("Found %v fewer experts than wanted for #%v.", needed, pr.Number)
+	}
+	return nil
+}
+
+// pathHistory returns the authors and dates of the most recent commits to
+// a path on the default branch since a time.
+func (b *Bot) pathHistory(ctx context.Context, path string, since time.Time) ([]expertise.Commit, error) {
+	env := b.c.Environment
+	commits, _, err := b.c.GitHub.Repositories.ListCommits(ctx, env.Organization, env.Repository, &github.CommitsListOptions{
+		Path:        path,
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: perPage},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing commits to %v: %w", path, err)
+	}
+	var history []expertise.Commit
+	for _, commit := range commits {
+		history = append(history, expertise.Commit{
+			SHA:    commit.GetSHA(),
+			Author: commit.GetAuthor().GetLogin(),
+			Date:   commit.GetCommit().GetAuthor().GetDate(),
+		})
+	}
+	return history, nil
+}
+
+// isMember returns true if a user is a member of the organization. Results
+// are cached for the lifetime of the bot.
+func (b *Bot) isMember(ctx context.Context, login string) (bool, error) {
+	if member, ok := b.members[login]; ok {
+		return member, nil
+	}
+	member, _, err := b.c.GitHub.Organizations.IsMember(ctx, b.c.Environment.Organization, login)
+	if err != nil {
+		return false, fmt.Errorf("checking membership of %v: %w", login, err)
+	}
+	if b.members == nil {
+		b.members = make(map[string]bool)
+	}
+	b.members[login] = member
+	return member, nil
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// headRefForcePushed is the issue event recorded when a pull request
+// branch is force pushed.
+const headRefForcePushed = "head_ref_force_pushed"
+
+// expiry is when approvals of a pull request expire.
+type expiry struct {
+	// maxAge is how long before the latest push an approval still counts.
+	maxAge time.Duration
+	// pushed is when the head of the pull request was last pushed.
+	pushed time.Time
+}
+
+// expired returns true if an approval submitted at the given time is more
+// than maxAge older than the latest push.
+func (e *expiry) expired(submitted time.Time) bool {
+	return submitted.Before(e.pushed.Add(-e.maxAge))
+}
+
+// String describes the expiry for check output.
+func (e *expiry) String() string {
+	return fmt.Sprintf("approvals expire when given more than %v before the latest push at %v",
+		describeAge(e.maxAge), e.pushed.UTC().Format(time.RFC3339))
+}
+
+// approvalExpiry returns when approvals of a pull request expire, or nil if
+// approvals into its base branch do not.
+func (b *Bot) approvalExpiry(ctx context.Context, pr *environment.Metadata) (*expiry, error) {
+	maxAge := b.c.Settings.Approvals.MaxAgeFor(pr.BaseRef)
+	if maxAge == 0 {
+		return nil, nil
+	}
+	pushed, err := b.pushTime(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	return &expiry{maxAge: maxAge, pushed: pushed}, nil
+}
+
+// pushTime returns when the head of a pull request was last pushed: now if
+// the bot runs for the push, or the latest of the last force push and the
+// head commit's committer date. The committer date is set by the author,
+// so a backdated commit pushed without force can make approvals look newer
+// than they are. Results are cached for the lifetime of the bot.
+func (b *Bot) pushTime(ctx context.Context, pr *environment.Metadata) (time.Time, error) {
+	if t, ok := b.pushTimes[pr.Number]; ok {
+		return t, nil
+	}
+	env := b.c.Environment
+	now := time.Now()
+	var pushed time.Time
+	if env.Action == environment.Synchronize && env.Metadata != nil && env.Metadata.Number == pr.Number {
+		pushed = now
+	} else {
+		commit, _, err := b.c.GitHub.Repositories.GetCommit(ctx, env.Organization, env.Repository, pr.HeadSHA)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("fetching commit %v: %w", shortSHA(pr.HeadSHA), err)
+		}
+		if committed := commit.GetCommit().GetCommitter().GetDate(); committed.Before(now) {
+			pushed = committed
+		} else {
+			pushed = now
+		}
+		forced, err := b.lastForcePush(ctx, pr.Number)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if forced.After(pushed) {
+			pushed = forced
+		}
+	}
+	if b.pushTimes == nil {
+		b.pushTimes = make(map[int]time.Time)
+	}
+	b.pushTimes[pr.Number] = pushed
+	return pushed, nil
+}
+
+// lastForcePush returns when a pull request branch was last force pushed,
+// or the zero time if it never was.
+func (b *Bot) lastForcePush(ctx context.Context, number int) (time.Time, error) {
+	env := b.c.Environment
+	var last time.Time
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		events, resp, err := b.c.GitHub.Issues.ListIssueEvents(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("listing events: %w", err)
+		}
+		for _, event := range events {
+			if at := event.GetCreatedAt(); event.GetEvent() == headRefForcePushed && at.After(last) {
+				last = at
+			}
+		}
+		if resp.NextPage == 0 {
+			return last, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryExpired(t *testing.T) {
+	pushed := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	e := &expiry{maxAge: 14 * 24 * time.Hour, pushed: pushed}
+	tests := []struct {
+		name      string
+		submitted time.Time
+		expired   bool
+	}{
+		{name: "after the push", submitted: pushed.Add(time.Hour)},
+		{name: "at the push", submitted: pushed},
+		{name: "within the maximum age", submitted: pushed.Add(-13 * 24 * time.Hour)},
+		{name: "exactly the maximum age", submitted: pushed.Add(-14 * 24 * time.Hour)},
+		{name: "older than the maximum age", submitted: pushed.Add(-14*24*time.Hour - time.Second), expired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.expired(tt.submitted); got != tt.expired {
+				t.Errorf("expired(%v) = %v, want %v", tt.submitted, got, tt.expired)
+			}
+		})
+	}
+	if want := "approvals expire when given more than 14 days before the latest push at 2021-06-15T12:00:00Z"; e.String() != want {
+		t.Errorf("String() = %q, want %q", e.String(), want)
+	}
+}
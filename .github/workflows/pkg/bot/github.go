@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/policy"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// perPage is the page size used when listing resources.
+const perPage = 100
+
+// botLogin is the account the workflow token acts as. Only its comments
+// are trusted to hold the bot's markers, since other bots and apps, and
+// people, can post arbitrary text.
+const botLogin = "github-actions[bot]"
+
+// isBot returns true if a user is the bot itself.
+func isBot(user *github.User) bool {
+	return user.GetLogin() == botLogin && user.GetType() == "Bot"
+}
+
+// listCommits returns every commit in a pull request.
+func (b *Bot) listCommits(ctx context.Context, number int) ([]*github.RepositoryCommit, error) {
+	env := b.c.Environment
+	var all []*github.RepositoryCommit
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		commits, resp, err := b.c.GitHub.PullRequests.ListCommits(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, commits...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// listReviews returns every review submitted on a pull request, oldest first.
+func (b *Bot) listReviews(ctx context.Context, number int) ([]*github.PullRequestReview, error) {
+	env := b.c.Environment
+	var all []*github.PullRequestReview
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		reviews, resp, err := b.c.GitHub.PullRequests.ListReviews(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, reviews...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// latestReviews reduces reviews to the most recent review of each reviewer.
+// Comments do not change a reviewer's state, so they are ignored.
+func latestReviews(reviews []*github.PullRequestReview) map[string]*github.PullRequestReview {
+	latest := make(map[string]*github.PullRequestReview)
+	for _, review := range reviews {
+		if review.GetState() == commented {
+			continue
+		}
+		login := review.GetUser().GetLogin()
+		current, ok := latest[login]
+		if !ok || !review.GetSubmittedAt().Before(current.GetSubmittedAt()) {
+			latest[login] = review
+		}
+	}
+	return latest
+}
+
+// approvers returns the sorted logins whose latest review is an approval.
+func approvers(latest map[string]*github.PullRequestReview) []string {
+	var logins []string
+	for login, review := range latest {
+		if review.GetState() == policy.Approved {
+			logins = append(logins, login)
+		}
+	}
+	sort.Strings(logins)
+	return logins
+}
+
+// commented is the state of a review that only left comments.
+const commented = "COMMENTED"
+
+// listFiles returns every file changed by a pull request.
+func (b *Bot) listFiles(ctx context.Context, number int) ([]*github.CommitFile, error) {
+	env := b.c.Environment
+	var all []*github.CommitFile
+	opts := &github.ListOptions{PerPage: perPage}
+	for {
+		files, resp, err := b.c.GitHub.PullRequests.ListFiles(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, files...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// comment posts a comment on a pull request.
+func (b *Bot) comment(ctx context.Context, number int, body string) error {
+	env := b.c.Environment
+	_, _, err := b.c.GitHub.Issues.CreateComment(ctx, env.Organization, env.Repository, number, &github.IssueComment{
+		Body: &body,
+	})
+	if err != nil {
+		return fmt.Errorf("commenting on #%v: %w", number, err)
+	}
+	return nil
+}
+
+// stickyComment creates a bot comment on an issue or pull request, or
+// updates the bot comment containing the marker if there already is one.
+func (b *Bot) stickyComment(ctx context.Context, number int, marker, body string) error {
+	env := b.c.Environment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: perPage}}
+	for {
+		comments, resp, err := b.c.GitHub.Issues.ListComments(ctx, env.Organization, env.Repository, number, opts)
+		if err != nil {
+			return fmt.Errorf("listing comments: %w", err)
+		}
+		for _, comment := range comments {
+			if !isBot(comment.GetUser()) || !strings.Contains(comment.GetBody(), marker) {
+				continue
+			}
+			if comment.GetBody() == body {
+				return nil
+			}
+			_, _, err := b.c.GitHub.Issues.EditComment(ctx, env.Organization, env.Repository, comment.GetID(), &github.IssueComment{
+				Body: &body,
+			})
+			if err != nil {
+				return fmt.Errorf("updating comment %v on #%v: %w", comment.GetID(), number, err)
+			}
+			return nil
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return b.comment(ctx, number, body)
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/backport"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/contributor"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/policy"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// invalidateApprovals dismisses approvals of a pull request by an external
+// contributor or bot that were given before new commits were pushed. An
+// approval stays valid if every new commit is a merge of the base branch
+// made and signed by GitHub, e.g. with the "Update branch" button, or if the
+// pull request's changes are the same as when it was approved, e.g. after a
+// rebase onto the base branch. It returns true if any approval was
+// dismissed.
+func (b *Bot) invalidateApprovals(ctx context.Context, pr *environment.Metadata, reviews []*github.PullRequestReview) (bool, error) {
+	env := b.c.Environment
+	if env.EventName != environment.PullRequestTarget || env.Action != environment.Synchronize {
+		return false, nil
+	}
+	classification, err := b.classify(ctx, pr)
+	if err != nil {
+		return false, err
+	}
+	if classification == contributor.Internal {
+		return false, nil
+	}
+
+	commits, err := b.listCommits(ctx, pr.Number)
+	if err != nil {
+		return false, fmt.Errorf("listing commits: %w", err)
+	}
+	position := make(map[string]int)
+	for i, commit := range commits {
+		position[commit.GetSHA()] = i
+	}
+
+	// Whether a commit is a web-flow merge of the base branch, by SHA.
+	trivial := make(map[string]bool)
+	// Whether the changes at an approved commit match the head's, by SHA.
+	equivalent := make(map[string]bool)
+	dismissed := false
+	for _, review := range reviews {
+		if review.GetState() != policy.Approved {
+			continue
+		}
+		// Every commit after the approved one is new. If the approved
+		// commit is gone the branch was rewritten, so every commit is new.
+		newCommits := commits
+		if i, ok := position[review.GetCommitID()]; ok {
+			newCommits = commits[i+1:]
+		}
+
+		var untrusted []string
+		for _, commit := range newCommits {
+			sha := commit.GetSHA()
+			if _, ok := trivial[sha]; !ok {
+				err := b.checkBaseMerge(ctx, pr, commit)
+				if err != nil {
+					log.Printf("Commit %v is not a web-flow merge of %v: %v.", sha, pr.BaseRef, err)
+				}
+				trivial[sha] = err == nil
+			}
+			if !trivial[sha] {
+				untrusted = append(untrusted, shortSHA(sha))
+			}
+		}
+		if len(untrusted) == 0 {
+			continue
+		}
+		login := review.GetUser().GetLogin()
+		approved := review.GetCommitID()
+		if _, ok := equivalent[approved]; !ok {
+			same, err := b.sameChanges(ctx, pr, approved)
+			if err != nil {
+				return dismissed, err
+			}
+			equivalent[approved] = same
+		}
+		if equivalent[approved] {
+			log.Printf("Keeping approval by %v on #%v: the changes at %v only differ from %v by base branch changes.",
+				login, pr.Number, shortSHA(pr.HeadSHA), shortSHA(approved))
+			continue
+		}
+
+		message := fmt.Sprintf("Approval dismissed: new commits %v were pushed after this review by an external contributor. Please review again.",
+			strings.Join(untrusted, ", "))
+		_, _, err := b.c.GitHub.PullRequests.DismissReview(ctx, env.Organization, env.Repository, pr.Number, review.GetID(),
+			&github.PullRequestReviewDismissalRequest{Message: &message})
+		if err != nil {
+			return dismissed, fmt.Errorf("dismissing approval by %v: %w", login, err)
+		}
+		log.Printf("Dismissed approval by %v on #%v: new commits %v.", login, pr.Number, untrusted)
+		dismissed = true
+	}
+	return dismissed, nil
+}
+
+// sameChanges returns true if a pull request changes the same lines at an
+// earlier commit as at its head, like an empty git range-diff. Each side is
+// diffed against its merge base with the base branch and compared by patch
+// ID, so line numbers, context lines and base branch changes are ignored.
+// A commit that can no longer be compared, e.g. one garbage collected after
+// a force push, and one that changes too many files to be listed in full
+// never match.
+func (b *Bot) sameChanges(ctx context.Context, pr *environment.Metadata, sha string) (bool, error) {
+	if sha == "" {
+		return false, nil
+	}
+	before, complete, err := b.compareFiles(ctx, pr.BaseRef, sha)
+	if err != nil {
+		log.Printf("Cannot compare %v with %v: %v.", shortSHA(sha), pr.BaseRef, err)
+		return false, nil
+	}
+	if !complete {
+		log.Printf("Changes at %v touch too many files to compare.", shortSHA(sha))
+		return false, nil
+	}
+	after, complete, err := b.compareFiles(ctx, pr.BaseRef, pr.HeadSHA)
+	if err != nil {
+		return false, err
+	}
+	if !complete {
+		log.Printf("Changes at %v touch too many files to compare.", shortSHA(pr.HeadSHA))
+		return false, nil
+	}
+	difference := backport.Compare(before, after)
+	if len(difference.Files) > 0 {
+		log.Printf("Changes at %v and %v differ in %v.", shortSHA(sha), shortSHA(pr.HeadSHA), difference.Files)
+	}
+	return difference.Within(0), nil
+}
+
+// checkBaseMerge returns an error unless commit is a merge commit created
+// and signed by GitHub that merges the base branch into the pull request.
+func (b *Bot) checkBaseMerge(ctx context.Context, pr *environment.Metadata, commit *github.RepositoryCommit) error {
+	env := b.c.Environment
+	if len(commit.Parents) != 2 {
+		return fmt.Errorf("not a merge commit")
+	}
+	if login := commit.GetCommitter().GetLogin(); login != webFlowLogin {
+		return fmt.Errorf("committed by %v", login)
+	}
+	if err := verifyWebFlowSignature(commit.GetCommit().GetVerification()); err != nil {
+		return err
+	}
+
+	// The merged parent must already be part of the base branch, i.e. the
+	// base branch is identical to or ahead of it.
+	merged := commit.Parents[1].GetSHA()
+	comparison, _, err := b.c.GitHub.Repositories.CompareCommits(ctx, env.Organization, env.Repository, pr.BaseRef, merged)
+	if err != nil {
+		return fmt.Errorf("comparing %v with %v: %w", merged, pr.BaseRef, err)
+	}
+	switch status := comparison.GetStatus(); status {
+	case "behind", "identical":
+		return nil
+	default:
+		return fmt.Errorf("merged commit %v is %v %v", shortSHA(merged), status, pr.BaseRef)
+	}
+}
+
+// shortSHA abbreviates a commit SHA for display.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// candidate is a reviewer and their number of outstanding review requests.
+type candidate struct {
+	reviewer string
+	load     int
+}
+
+// rankByLoad orders the members of a group by their number of outstanding
+// review requests on other open pull requests, and returns how many of them
+// are needed. Members already reviewing the pull request count towards the
+// group's count and are not ranked. Ties are broken by a hash of the pull
+// request number and reviewer, so that equally loaded reviewers take turns.
+func (b *Bot) rankByLoad(ctx context.Context, pr *environment.Metadata, group config.Group, existing map[string]bool) (int, []candidate, error) {
+	needed := group.Count
+	var candidates []candidate
+	for _, reviewer := range group.Reviewers {
+		if existing[reviewer] {
+			needed--
+			continue
+		}
+		candidates = append(candidates, candidate{reviewer: reviewer})
+	}
+	if needed <= 0 || len(candidates) == 0 {
+		return 0, nil, nil
+	}
+
+	load, err := b.reviewLoad(ctx, pr.Number)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i := range candidates {
+		candidates[i].load = load[candidates[i].reviewer]
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].load != candidates[j].load {
+			return candidates[i].load < candidates[j].load
+		}
+		return tieBreak(pr.Number, candidates[i].reviewer) < tieBreak(pr.Number, candidates[j].reviewer)
+	})
+	return needed, candidates, nil
+}
+
+// reviewLoad returns the number of open pull requests, other than the given
+// one, each user's review is requested on.
+func (b *Bot) reviewLoad(ctx context.Context, exclude int) (map[string]int, error) {
+	requests, err := b.openReviewRequests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	load := make(map[string]int)
+	for number, reviewers := range requests {
+		if number == exclude {
+			continue
+		}
+		for _, reviewer := range reviewers {
+			load[reviewer]++
+		}
+	}
+	return load, nil
+}
+
+// openReviewRequests returns the users whose review is requested on each
+// open pull request. The result is cached for the lifetime of the bot.
+func (b *Bot) openReviewRequests(ctx context.Context) (map[int][]string, error) {
+	if b.requests != nil {
+		return b.requests, nil
+	}
+	prs, err := b.listOpenPullRequests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requests := make(map[int][]string)
+	for _, pr := range prs {
+		for _, user := range pr.RequestedReviewers {
+			requests[pr.GetNumber()] = append(requests[pr.GetNumber()], user.GetLogin())
+		}
+	}
+	b.requests = requests
+	return requests, nil
+}
+
+// listOpenPullRequests returns every open pull request in the repository.
+func (b *Bot) listOpenPullRequests(ctx context.Context) ([]*github.PullRequest, error) {
+	env := b.c.Environment
+	var all []*github.PullRequest
+	opts := &github.PullRequestListOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: perPage},
+	}
+	for {
+		prs, resp, err := b.c.GitHub.PullRequests.List(ctx, env.Organization, env.Repository, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing open pull requests: %w", err)
+		}
+		all = append(all, prs...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// tieBreak is a deterministic per pull request ordering of reviewers.
+func tieBreak(number int, reviewer string) uint32 {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%v/%v", number, reviewer)
+	return h.Sum32()
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+)
+
+// RecheckConfig configures RecheckReviews.
+type RecheckConfig struct {
+	// CheckWorkflow is the file name of the workflow that checks reviews.
+	CheckWorkflow string
+}
+
+// RecheckReviews re-evaluates the reviews of open pull requests into
+// branches where approvals expire, and re-runs the check workflow of those
+// whose latest check result no longer matches, e.g. because a push expired
+// approvals but its check did not run, or the maximum age was lowered.
+func (b *Bot) RecheckReviews(ctx context.Context, c RecheckConfig) error {
+	prs, err := b.listOpenPullRequests(ctx)
+	if err != nil {
+		return err
+	}
+	for _, pr := range prs {
+		if pr.GetDraft() || b.c.Settings.Approvals.MaxAgeFor(pr.GetBase().GetRef()) == 0 {
+			continue
+		}
+		if err := b.recheckPullRequest(ctx, environment.NewMetadata(pr), c.CheckWorkflow); err != nil {
+			return fmt.Errorf("rechecking #%v: %w", pr.GetNumber(), err)
+		}
+	}
+	return nil
+}
+
+// recheckPullRequest re-runs the check workflow of a pull request if its
+// latest completed run passed but the pull request is no longer approved,
+// or the other way round.
+func (b *Bot) recheckPullRequest(ctx context.Context, pr *environment.Metadata, workflow string) error {
+	run, err := b.latestCheckRun(ctx, pr, workflow)
+	if err != nil {
+		return err
+	}
+	if run == nil || run.GetStatus() != "completed" {
+		return nil
+	}
+	skipped, err := b.skippedReview(ctx, pr)
+	if err != nil {
+		return err
+	}
+	if skipped != nil {
+		return nil
+	}
+	reviews, err := b.listReviews(ctx, pr.Number)
+	if err != nil {
+		return fmt.Errorf("listing reviews: %w", err)
+	}
+	evaluation, err := b.evaluate(ctx, pr, reviews)
+	if err != nil {
+		return err
+	}
+	passed := run.GetConclusion() == "success"
+	if evaluation.Approved() == passed {
+		return nil
+	}
+	log.Printf("Check run %v of #%v is %v but the reviews now evaluate to:\n%v", run.GetID(), pr.Number, run.GetConclusion(), evaluation.Explain())
+	return b.rerunCheck(ctx, pr, workflow)
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/risk"
+)
+
+// riskScore returns the risk score of a pull request. Scores are cached
+// for the lifetime of the bot.
+func (b *Bot) riskScore(ctx context.Context, number int) (risk.Score, error) {
+	if score, ok := b.scores[number]; ok {
+		return score, nil
+	}
+	files, err := b.listFiles(ctx, number)
+	if err != nil {
+		return risk.Score{}, fmt.Errorf("listing files: %w", err)
+	}
+	var changed []risk.File
+	for _, file := range files {
+		changed = append(changed, risk.File{
+			Path:     file.GetFilename(),
+			Previous: file.GetPreviousFilename(),
+			Changes:  file.GetChanges(),
+		})
+	}
+	score := risk.Compute(changed, b.c.Settings.Risk.SensitiveRules())
+	if b.scores == nil {
+		b.scores = make(map[int]risk.Score)
+	}
+	b.scores[number] = score
+	return score, nil
+}
+
+// requiredApprovals returns the number of approvals a pull request needs:
+// the configured number, or more if its risk tier requires more. It also
+// returns true if the risk tier raised the number.
+func (b *Bot) requiredApprovals(ctx context.Context, number int) (int, bool, error) {
+	score, err := b.riskScore(ctx, number)
+	if err != nil {
+		return 0, false, err
+	}
+	approvals := b.c.Settings.Approvals.Required
+	if tier := b.c.Settings.Risk.TierFor(score.Value); tier.Approvals > approvals {
+		return tier.Approvals, true, nil
+	}
+	return approvals, false, nil
+}
+
+// selectForRisk requests additional reviewers from the author's reviewers
+// until the pull request has as many as its risk tier requires. The least
+// loaded available reviewers are chosen.
+func (b *Bot) selectForRisk(ctx context.Context, pr *environment.Metadata, existing map[string]bool, selected *selections) error {
+	score, err := b.riskScore(ctx, pr.Number)
+	if err != nil {
+		return err
+	}
+	tier := b.c.Settings.Risk.TierFor(score.Value)
+	reviewers := make(map[string]bool)
+	for reviewer := range existing {
+		reviewers[reviewer] = true
+	}
+	for _, s := range selected.chosen {
+		reviewers[s.reviewer] = true
+	}
+	needed := tier.Reviewers - len(reviewers)
+	if needed <= 0 {
+		return nil
+	}
+
+	pool, err := b.expandTeams(ctx, b.c.Settings.ReviewersFor(pr.Author), pr.Author)
+	if err != nil {
+		return err
+	}
+	var candidates []string
+	for _, reviewer := range pool {
+		if !reviewers[reviewer] {
+			candidates = append(candidates, reviewer)
+		}
+	}
+	_, ranked, err := b.rankByLoad(ctx, pr, config.Group{Reviewers: candidates, Count: needed}, existing)
+	if err != nil {
+		return err
+	}
+	calendar, err := b.calendar(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, c := range ranked {
+		if needed == 0 {
+			break
+		}
+		if selected.passOver(c.reviewer, calendar.At(c.reviewer, now)) {
+			continue
+		}
+		selected.add(c.reviewer, false, fmt.Sprintf("additional reviewer for %v, least loaded with %v open review requests", score, c.load))
+		needed--
+	}
+	return nil
+}
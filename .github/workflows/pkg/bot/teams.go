@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// teamMembers returns the logins of the members of an "@org/team" entry,
+// including the members of its child teams. Results are cached for the
+// lifetime of the bot.
+func (b *Bot) teamMembers(ctx context.Context, team string) ([]string, error) {
+	if members, ok := b.teams[team]; ok {
+		return members, nil
+	}
+	org, slug, err := config.ParseTeam(team)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var members []string
+	if err := b.collectTeamMembers(ctx, org, slug, make(map[string]bool), seen, &members); err != nil {
+		return nil, fmt.Errorf("resolving %v: %w", team, err)
+	}
+	if b.teams == nil {
+		b.teams = make(map[string][]string)
+	}
+	b.teams[team] = members
+	return members, nil
+}
+
+// collectTeamMembers appends the members of a team and its child teams
+// that have not been seen yet.
+func (b *Bot) collectTeamMembers(ctx context.Context, org, slug string, visited, seen map[string]bool, members *[]string) error {
+	if visited[slug] {
+		return nil
+	}
+	visited[slug] = true
+
+	opts := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: perPage}}
+	for {
+		page, resp, err := b.c.GitHub.Teams.ListTeamMembersBySlug(ctx, org, slug, opts)
+		if err != nil {
+			return fmt.Errorf("listing members of %v/%v: %w", org, slug, err)
+		}
+		for _, user := range page {
+			if login := user.GetLogin(); !seen[login] {
+				seen[login] = true
+				*members = append(*members, login)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	listOpts := &github.ListOptions{PerPage: perPage}
+	var children []string
+	for {
+		page, resp, err := b.c.GitHub.Teams.ListChildTeamsByParentSlug(ctx, org, slug, listOpts)
+		if err != nil {
+			return fmt.Errorf("listing child teams of %v/%v: %w", org, slug, err)
+		}
+		for _, team := range page {
+			children = append(children, team.GetSlug())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		listOpts.Page = resp.NextPage
+	}
+	for _, child := range children {
+		if err := b.collectTeamMembers(ctx, org, child, visited, seen, members); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandTeams replaces the team entries in a list of reviewers with their
+// members. The author and duplicates are dropped.
+func (b *Bot) expandTeams(ctx context.Context, reviewers []string, author string) ([]string, error) {
+	var expanded []string
+	seen := map[string]bool{author: true}
+	for _, entry := range reviewers {
+		logins := []string{entry}
+		if config.IsTeam(entry) {
+			members, err := b.teamMembers(ctx, entry)
+			if err != nil {
+				return nil, err
+			}
+			logins = members
+		}
+		for _, login := range logins {
+			if !seen[login] {
+				seen[login] = true
+				expanded = append(expanded, login)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// hasTeams returns true if any reviewer entry is a team.
+func hasTeams(reviewers []string) bool {
+	for _, reviewer := range reviewers {
+		if config.IsTeam(reviewer) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/contributor"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/policy"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/risk"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// twoPersonRule is the two-person rule as it applies to a pull request.
+type twoPersonRule struct {
+	// sensitive are the changed paths the rule covers.
+	sensitive []string
+	// authors are the login of the pull request author followed by the
+	// sorted logins of the authors and co-authors of its commits.
+	authors []string
+	// unresolved are the emails of co-authors without a known GitHub
+	// account, who therefore cannot be excluded as approvers.
+	unresolved []string
+}
+
+// twoPerson returns the two-person rule for a pull request, or nil if the
+// pull request does not change sensitive paths. Results are cached for the
+// lifetime of the bot.
+func (b *Bot) twoPerson(ctx context.Context, pr *environment.Metadata) (*twoPersonRule, error) {
+	if rule, ok := b.twoPersonRules[pr.Number]; ok {
+		return rule, nil
+	}
+	rule, err := b.findTwoPerson(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if b.twoPersonRules == nil {
+		b.twoPersonRules = make(map[int]*twoPersonRule)
+	}
+	b.twoPersonRules[pr.Number] = rule
+	return rule, nil
+}
+
+// findTwoPerson finds the sensitive paths and the authors of a pull
+// request.
+func (b *Bot) findTwoPerson(ctx context.Context, pr *environment.Metadata) (*twoPersonRule, error) {
+	settings := b.c.Settings.TwoPerson
+	files, err := b.listFiles(ctx, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("listing files: %w", err)
+	}
+	// A file moved or renamed away from a sensitive path changes it too.
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.GetFilename())
+		if previous := file.GetPreviousFilename(); previous != "" {
+			paths = append(paths, previous)
+		}
+	}
+	sensitive := settings.Sensitive(paths)
+	if len(sensitive) == 0 {
+		return nil, nil
+	}
+
+	commits, err := b.listCommits(ctx, pr.Number)
+	if err != nil {
+		return nil, fmt.Errorf("listing commits: %w", err)
+	}
+	rule := &twoPersonRule{sensitive: sensitive, authors: []string{pr.Author}}
+	logins := make(map[string]string)
+	var emails []string
+	for _, commit := range commits {
+		email := strings.ToLower(commit.GetCommit().GetAuthor().GetEmail())
+		if login := commit.GetAuthor().GetLogin(); login != "" {
+			logins[email] = login
+		} else {
+			emails = append(emails, email)
+		}
+		for _, coAuthor := range contributor.CoAuthors(commit.GetCommit().GetMessage()) {
+			emails = append(emails, coAuthor.Email)
+		}
+	}
+	for _, login := range logins {
+		if !contains(rule.authors, login) {
+			rule.authors = append(rule.authors, login)
+		}
+	}
+	for _, email := range emails {
+		login, err := b.loginForEmail(ctx, email, logins)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case login == "":
+			if !contains(rule.unresolved, email) {
+				rule.unresolved = append(rule.unresolved, email)
+			}
+		case !contains(rule.authors, login):
+			rule.authors = append(rule.authors, login)
+		}
+	}
+	// Map order is random, so sort for stable check output and comments.
+	sort.Strings(rule.authors[1:])
+	sort.Strings(rule.unresolved)
+	return rule, nil
+}
+
+// loginForEmail returns the GitHub login of a commit email: from a private
+// commit email, a commit in the pull request or a user search. It returns
+// an empty login if the email cannot be matched to exactly one account.
+func (b *Bot) loginForEmail(ctx context.Context, email string, known map[string]string) (string, error) {
+	if login, ok := contributor.NoReplyLogin(email); ok {
+		return login, nil
+	}
+	if login, ok := known[email]; ok {
+		return login, nil
+	}
+	result, _, err := b.c.GitHub.Search.Users(ctx, email+" in:email", &github.SearchOptions{})
+	if err != nil {
+		return "", fmt.Errorf("searching for the user with email %v: %w", email, err)
+	}
+	if result.GetTotal() != 1 || len(result.Users) != 1 {
+		return "", nil
+	}
+	login := result.Users[0].GetLogin()
+	known[email] = login
+	return login, nil
+}
+
+// twoPersonRequirement returns the approvals the two-person rule requires:
+// distinct members of the security group who are not authors.
+func (b *Bot) twoPersonRequirement(ctx context.Context, pr *environment.Metadata, rule *twoPersonRule) (policy.Requirement, error) {
+	settings := b.c.Settings.TwoPerson
+	group, _ := b.c.Settings.GroupFor(settings.Group, pr.Author)
+	members, err := b.expandTeams(ctx, group.Reviewers, pr.Author)
+	if err != nil {
+		return policy.Requirement{}, err
+	}
+	var eligible []string
+	for _, member := range members {
+		if !contains(rule.authors, member) {
+			eligible = append(eligible, member)
+		}
+	}
+	return policy.Requirement{
+		Name: fmt.Sprintf("two-person rule for %v: approval from members of group %v other than the authors %v",
+			risk.DescribePaths(rule.sensitive), group.Name, strings.Join(rule.authors, ", ")),
+		Reviewers: eligible,
+		Approvals: settings.Approvals,
+	}, nil
+}
+
+// twoPersonCondition requires every co-author to be known, since unknown
+// co-authors cannot be excluded as approvers.
+func twoPersonCondition(rule *twoPersonRule) policy.Condition {
+	if len(rule.unresolved) == 0 {
+		return policy.Condition{Name: "two-person rule: every co-author has a known GitHub account", Met: true}
+	}
+	return policy.Condition{
+		Name: fmt.Sprintf("two-person rule: co-authors %v have no known GitHub account and cannot be excluded as approvers",
+			strings.Join(rule.unresolved, ", ")),
+	}
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// webFlowLogin is the committer of commits GitHub creates on behalf of users
+// in the web interface.
+const webFlowLogin = "web-flow"
+
+// webFlowFingerprints are the fingerprints of the keys GitHub signs web
+// commits with. The Check workflow imports them into the gpg key ring.
+var webFlowFingerprints = []string{
+	"5DE3E0509C47EA3CF04A42D34AEE18F83AFDEB23",
+	"968479A1AFF927E37D1A566BB5690EEEBB952194",
+}
+
+// verifyWebFlowSignature checks with gpg that a commit signature was made
+// by GitHub's web-flow key.
+func verifyWebFlowSignature(verification *github.SignatureVerification) error {
+	if verification.GetSignature() == "" || verification.GetPayload() == "" {
+		return fmt.Errorf("commit is not signed")
+	}
+
+	dir, err := ioutil.TempDir("", "webflow")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	dataFile := dir + "/data"
+	sigFile := dir + "/signature"
+	if err := ioutil.WriteFile(dataFile, []byte(verification.GetPayload()), 0600); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(sigFile, []byte(verification.GetSignature()), 0600); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("gpg", "--status-fd=1", "--verify", sigFile, dataFile)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("gpg --verify: %w: %v", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// A good signature produces a status line of the form
+	// "[GNUPG:] VALIDSIG <fingerprint> ... <primary key fingerprint>".
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "VALIDSIG" {
+			continue
+		}
+		for _, fingerprint := range webFlowFingerprints {
+			if fields[2] == fingerprint || fields[len(fields)-1] == fingerprint {
+				return nil
+			}
+		}
+		return fmt.Errorf("signed by %v, not web-flow", fields[2])
+	}
+	return fmt.Errorf("gpg did not report a valid signature")
+}
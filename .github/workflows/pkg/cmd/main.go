@@ -3,21 +3,474 @@ package main
 import (
 	"bytes"
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/attest"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/bot"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/config"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/contributor"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/environment"
 
 	"github.com/google/go-github/v37/github"
 )
 
+// globalFlags are the flags shared by every command. They precede the
+// command name on the command line.
+type globalFlags struct {
+	// token is the GitHub token used to authenticate API requests.
+	token string
+	// reviewers is a JSON map of author to reviewers.
+	reviewers string
+	// requiredApprovals is the number of approvals a pull request needs.
+	requiredApprovals int
+	// bots is a comma separated allowlist of bot accounts.
+	bots string
+}
+
 func main() {
-	err := verifySig()
+	var g globalFlags
+	flag.StringVar(&g.token, "token", "", "GitHub token used to authenticate API requests")
+	flag.StringVar(&g.reviewers, "reviewers", "", `JSON map of author to reviewers, "*" is the fallback for other authors (only used without `+config.Path+`)`)
+	flag.IntVar(&g.requiredApprovals, "required-approvals", 1, "number of approvals a pull request needs from the author's reviewers (only used without "+config.Path+")")
+	flag.StringVar(&g.bots, "bots", strings.Join(contributor.DefaultBots, ","), "comma separated allowlist of bot accounts (only used without "+config.Path+")")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := run(context.Background(), &g, flag.Arg(0), flag.Args()[1:])
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: %v [flags] <command> [command flags]
+
+Commands:
+  assign-reviewers    request reviews on the pull request in the event payload
+  check-reviewers     check the pull request in the event payload is approved
+  dismiss-runs        cancel superseded runs of the Check workflow
+  escalate-reviews    remind and replace reviewers who have not responded
+  recheck-reviews     re-run review checks whose result changed as approvals expired
+  handle-comment      run the slash commands in the comment in the event payload
+  validate-config     check the review bot configuration is valid
+  codeowners lint     check CODEOWNERS for problems and report unowned files
+  availability        list which reviewers are available on a date
+  verify-commit       verify a commit signature with the local gpg key ring
+  attest              produce a signed attestation for a verified pull request
+  verify-attestation  check the signature and contents of an attestation
+  audit-keys          report problems with the GPG keys of organization members
+
+Flags:
+`, os.Args[0])
+	flag.PrintDefaults()
+}
+
+func run(ctx context.Context, g *globalFlags, command string, args []string) error {
+	switch command {
+	case "assign-reviewers":
+		return assignReviewers(ctx, g, args)
+	case "check-reviewers":
+		return checkReviewers(ctx, g)
+	case "dismiss-runs":
+		return dismissRuns(ctx, g, args)
+	case "escalate-reviews":
+		return escalateReviews(ctx, g, args)
+	case "recheck-reviews":
+		return recheckReviews(ctx, g, args)
+	case "handle-comment":
+		return handleComment(ctx, g, args)
+	case "validate-config":
+		return validateConfig(ctx, g, args)
+	case "availability":
+		return listAvailability(ctx, g, args)
+	case "codeowners":
+		if len(args) == 0 || args[0] != "lint" {
+			return fmt.Errorf("usage: codeowners lint [flags]")
+		}
+		return lintCodeOwners(ctx, g, args[1:])
+	case "verify-commit":
+		return verifySig()
+	case "attest":
+		return attestPullRequest(ctx, g, args)
+	case "verify-attestation":
+		return verifyAttestation(args)
+	case "audit-keys":
+		return auditKeys(ctx, g, args)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// newBot returns a bot for the workflow run this process is part of.
+func newBot(ctx context.Context, g *globalFlags) (*bot.Bot, error) {
+	env, err := environment.New()
+	if err != nil {
+		return nil, err
+	}
+	client := newClient(g.token)
+	settings, err := loadSettings(ctx, client, env, g)
+	if err != nil {
+		return nil, err
+	}
+	return bot.New(&bot.Config{
+		GitHub:      client,
+		Environment: env,
+		Settings:    settings,
+	})
+}
+
+// loadSettings reads the configuration from the default branch of the
+// repository, falling back to the global flags if it does not have one.
+func loadSettings(ctx context.Context, client *github.Client, env *environment.Environment, g *globalFlags) (*config.Config, error) {
+	settings, err := config.Load(ctx, client, env.Organization, env.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil {
+		return settings, nil
+	}
+
+	log.Printf("%v not found on the default branch, using flags.", config.Path)
+	reviewers, err := config.ParseReviewers(g.reviewers)
+	if err != nil {
+		return nil, err
+	}
+	settings = &config.Config{
+		Version:   config.Version,
+		Reviewers: reviewers,
+		Approvals: config.Approvals{Required: g.requiredApprovals},
+	}
+	if g.bots != "" {
+		settings.Bots = strings.Split(g.bots, ",")
+	}
+	if err := settings.CheckAndSetDefaults(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// newClient returns a GitHub client authenticated with token, or an
+// unauthenticated client if token is empty.
+func newClient(token string) *github.Client {
+	if token == "" {
+		return github.NewClient(nil)
+	}
+	return github.NewClient(&http.Client{Transport: &tokenTransport{token: token}})
+}
+
+// tokenTransport adds a token to the Authorization header of every request.
+type tokenTransport struct {
+	token string
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "token "+t.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func assignReviewers(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("assign-reviewers", flag.ExitOnError)
+	cache := fs.String("expertise-cache", "", "file the commit history of changed paths is cached in between runs")
+	fs.Parse(args)
+
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	return b.Assign(ctx, bot.AssignConfig{ExpertiseCache: *cache})
+}
+
+func checkReviewers(ctx context.Context, g *globalFlags) error {
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	return b.Check(ctx)
+}
+
+func dismissRuns(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("dismiss-runs", flag.ExitOnError)
+	workflow := fs.String("workflow", "check.yml", "file name of the workflow whose runs are dismissed")
+	fs.Parse(args)
+
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	return b.DismissRuns(ctx, *workflow)
+}
+
+func escalateReviews(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("escalate-reviews", flag.ExitOnError)
+	fs.Parse(args)
+
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	return b.EscalateReviews(ctx)
+}
+
+func recheckReviews(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("recheck-reviews", flag.ExitOnError)
+	workflow := fs.String("check-workflow", "check.yml", "file name of the workflow that checks reviews")
+	fs.Parse(args)
+
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	return b.RecheckReviews(ctx, bot.RecheckConfig{CheckWorkflow: *workflow})
+}
+
+func handleComment(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("handle-comment", flag.ExitOnError)
+	workflow := fs.String("check-workflow", "check.yml", "file name of the workflow /recheck re-runs")
+	fs.Parse(args)
+
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	return b.HandleComment(ctx, bot.CommentConfig{CheckWorkflow: *workflow})
+}
+
+func validateConfig(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	path := fs.String("file", "", "configuration file to validate, defaults to "+config.Path+" on the default branch")
+	org := fs.String("organization", "", "organization the teams in the file must belong to, not checked if empty")
+	fs.Parse(args)
+
+	if *path != "" {
+		data, err := ioutil.ReadFile(*path)
+		if err != nil {
+			return err
+		}
+		settings, err := config.Parse(data)
+		if err != nil {
+			return err
+		}
+		if *org != "" {
+			if err := settings.CheckOrganization(*org); err != nil {
+				return err
+			}
+		}
+		log.Printf("%v is valid.", *path)
+		return nil
+	}
+
+	env, err := environment.New()
+	if err != nil {
+		return err
+	}
+	settings, err := config.Load(ctx, newClient(g.token), env.Organization, env.Repository)
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		return fmt.Errorf("%v not found on the default branch", config.Path)
+	}
+	log.Printf("%v on the default branch is valid.", config.Path)
+	return nil
+}
+
+func lintCodeOwners(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("codeowners lint", flag.ExitOnError)
+	path := fs.String("file", "", "CODEOWNERS file to lint, defaults to the one on the default branch")
+	output := fs.String("output", "", "file to write the report to, defaults to stdout")
+	fs.Parse(args)
+
+	var file *codeowners.File
+	if *path != "" {
+		data, err := ioutil.ReadFile(*path)
+		if err != nil {
+			return err
+		}
+		file = codeowners.Parse(data)
+	}
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	report, err := b.LintCodeOwners(ctx, file, *path)
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := report.WriteMarkdown(w); err != nil {
+		return err
+	}
+	if len(report.Problems) > 0 {
+		return fmt.Errorf("found %v problems in %v", len(report.Problems), report.Path)
+	}
+	return nil
+}
+
+func listAvailability(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("availability", flag.ExitOnError)
+	date := fs.String("date", time.Now().UTC().Format("2006-01-02"), "date to check in YYYY-MM-DD form")
+	fs.Parse(args)
+
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	statuses, err := b.Availability(ctx, *date)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reviewer availability on %v:\n", *date)
+	for _, status := range statuses {
+		fmt.Printf("  %-20v %v\n", status.Reviewer, status)
+	}
+	return nil
+}
+
+func attestPullRequest(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("attest", flag.ExitOnError)
+	number := fs.Int("pr", 0, "pull request to attest, defaults to the one in the event payload")
+	keyPath := fs.String("signing-key", "", "armored OpenPGP private key, its passphrase is read from $SIGNING_KEY_PASSPHRASE")
+	output := fs.String("output", "", "file to write the attestation to, defaults to stdout")
+	fs.Parse(args)
+	if *keyPath == "" {
+		return fmt.Errorf("--signing-key is required")
+	}
+
+	signer, err := attest.ReadSigningKey(*keyPath, []byte(os.Getenv("SIGNING_KEY_PASSPHRASE")))
+	if err != nil {
+		return err
+	}
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	statement, err := b.Attest(ctx, bot.AttestConfig{
+		Number: *number,
+	})
+	if err != nil {
+		return err
+	}
+	envelope, err := attest.Sign(statement, signer)
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return attest.WriteEnvelope(w, envelope)
+}
+
+// openOutput opens the file at path for writing, or stdout if path is empty.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func verifyAttestation(args []string) error {
+	fs := flag.NewFlagSet("verify-attestation", flag.ExitOnError)
+	path := fs.String("attestation", "", "attestation to verify")
+	keyPath := fs.String("public-key", "", "armored OpenPGP public key ring of trusted signers")
+	sha := fs.String("sha", "", "commit the attestation must be about")
+	fs.Parse(args)
+	if *path == "" || *keyPath == "" {
+		return fmt.Errorf("--attestation and --public-key are required")
+	}
+
+	keyring, err := attest.ReadKeyRing(*keyPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	envelope, err := attest.ReadEnvelope(f)
+	if err != nil {
+		return err
+	}
+	statement, err := attest.Verify(envelope, keyring)
+	if err != nil {
+		return err
+	}
+
+	predicate := statement.Predicate
+	if *sha != "" && statement.HeadSHA() != *sha {
+		return fmt.Errorf("attestation is about commit %v, not %v", statement.HeadSHA(), *sha)
+	}
+	if !predicate.Policy.Satisfied() {
+		return fmt.Errorf("attestation records that policy %q was not satisfied", predicate.Policy.Name)
+	}
+	log.Printf("Verified attestation for %v#%v at %v, approved by %v.",
+		predicate.Repository, predicate.PullRequest, statement.HeadSHA(), predicate.Approvers)
+	return nil
+}
+
+func auditKeys(ctx context.Context, g *globalFlags, args []string) error {
+	fs := flag.NewFlagSet("audit-keys", flag.ExitOnError)
+	days := fs.Int("days", 30, "report keys expiring within this many days")
+	minRSABits := fs.Int("min-rsa-bits", 2048, "report RSA keys shorter than this as weak")
+	format := fs.String("format", "markdown", "output format, markdown or json")
+	output := fs.String("output", "", "file to write the report to, defaults to stdout")
+	fs.Parse(args)
+	if *format != "markdown" && *format != "json" {
+		return fmt.Errorf("unsupported format %q", *format)
+	}
+
+	b, err := newBot(ctx, g)
+	if err != nil {
+		return err
+	}
+	report, err := b.AuditKeys(ctx, bot.AuditKeysConfig{
+		ExpiryWindow: time.Duration(*days) * 24 * time.Hour,
+		MinRSABits:   *minRSABits,
+	})
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(*output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if *format == "json" {
+		return report.WriteJSON(w)
+	}
+	return report.WriteMarkdown(w)
+}
+
 func verifySig() error {
 	client := github.NewClient(nil)
 	commit, _, err := client.Repositories.GetCommit(context.TODO(), "gravitational", "teleport", "f4ee52191cce728dd19ddd34c72bbe8858a281db") //api request
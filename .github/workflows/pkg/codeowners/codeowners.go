@@ -0,0 +1,207 @@
+// Package codeowners parses CODEOWNERS files and matches paths against them
+// with the semantics GitHub uses.
+//
+// Patterns follow gitignore rules with GitHub's restrictions: negation and
+// character ranges are not supported, and the last matching rule wins.
+package codeowners
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Paths are the locations GitHub looks for a CODEOWNERS file, in order.
+var Paths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// File is a parsed CODEOWNERS file.
+type File struct {
+	Rules []*Rule
+}
+
+// Rule is a single line of a CODEOWNERS file.
+type Rule struct {
+	// Line is the line number of the rule, starting at 1.
+	Line int
+	// Pattern is the path pattern as written.
+	Pattern string
+	// Owners are the owners as written: @user, @org/team or an email.
+	Owners []string
+	// Err is set if the pattern is invalid. Invalid rules never match.
+	Err error
+
+	re *regexp.Regexp
+}
+
+// String returns the rule as written in the file.
+func (r *Rule) String() string {
+	return strings.Join(append([]string{r.Pattern}, r.Owners...), " ")
+}
+
+// Match returns true if the rule matches a path relative to the
+// repository root.
+func (r *Rule) Match(path string) bool {
+	return r.re != nil && r.re.MatchString(strings.TrimPrefix(path, "/"))
+}
+
+// Parse parses the contents of a CODEOWNERS file. Rules with invalid
+// patterns are kept with Err set so they can be reported.
+func Parse(data []byte) *File {
+	var f File
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(stripComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+		rule := &Rule{
+			Line:    line,
+			Pattern: fields[0],
+			Owners:  fields[1:],
+		}
+		rule.re, rule.Err = compile(rule.Pattern)
+		f.Rules = append(f.Rules, rule)
+	}
+	return &f
+}
+
+// Compile returns an ownerless rule for a pattern, so that CODEOWNERS path
+// syntax can be used to match paths elsewhere.
+func Compile(pattern string) (*Rule, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Rule{Pattern: pattern, re: re}, nil
+}
+
+// Match returns the last rule matching path, or nil if no rule matches.
+// A matching rule without owners means the path has no owners.
+func (f *File) Match(path string) *Rule {
+	for i := len(f.Rules) - 1; i >= 0; i-- {
+		if f.Rules[i].Match(path) {
+			return f.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Owners returns the owners of path.
+func (f *File) Owners(path string) []string {
+	if rule := f.Match(path); rule != nil {
+		return rule.Owners
+	}
+	return nil
+}
+
+// IsTeam returns true if the owner is a team, e.g. @org/team.
+func IsTeam(owner string) bool {
+	return strings.HasPrefix(owner, "@") && strings.Contains(owner, "/")
+}
+
+// IsEmail returns true if the owner is an email address.
+func IsEmail(owner string) bool {
+	return !strings.HasPrefix(owner, "@") && strings.Contains(owner, "@")
+}
+
+// Login returns the user login or "org/team" of an owner without the
+// leading "@".
+func Login(owner string) string {
+	return strings.TrimPrefix(owner, "@")
+}
+
+// ValidOwner returns an error if owner is not a user, team or email.
+func ValidOwner(owner string) error {
+	switch {
+	case IsEmail(owner):
+		return nil
+	case IsTeam(owner):
+		parts := strings.Split(Login(owner), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid team %q", owner)
+		}
+		return nil
+	case strings.HasPrefix(owner, "@") && len(owner) > 1:
+		return nil
+	default:
+		return fmt.Errorf("invalid owner %q, expected @user, @org/team or an email", owner)
+	}
+}
+
+// stripComment removes a trailing comment from a line. A "#" preceded by a
+// backslash is part of the pattern.
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// compile translates a CODEOWNERS pattern to a regular expression matching
+// paths relative to the repository root.
+func compile(pattern string) (*regexp.Regexp, error) {
+	switch {
+	case strings.HasPrefix(pattern, "!"):
+		return nil, fmt.Errorf("negated pattern %q is not supported", pattern)
+	case strings.ContainsAny(pattern, "[]"):
+		return nil, fmt.Errorf("character range in %q is not supported", pattern)
+	}
+
+	// A pattern containing a slash other than a trailing one is relative to
+	// the repository root, otherwise it matches at any depth.
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty pattern %q", pattern)
+	}
+
+	var sb strings.Builder
+	if anchored {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		c := trimmed[i]
+		switch {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			// Leading or inner "**/" matches zero or more directories.
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(trimmed):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(trimmed[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	// A pattern naming a directory matches everything beneath it. GitHub
+	// does not extend this to wildcards in the last path segment: "docs/*"
+	// matches "docs/a.md" but not "docs/dir/a.md".
+	last := trimmed[strings.LastIndex(trimmed, "/")+1:]
+	switch {
+	case dirOnly:
+		sb.WriteString("/.*$")
+	case strings.ContainsAny(last, "*?"):
+		sb.WriteString("$")
+	default:
+		sb.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,134 @@
+package codeowners
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*", "a.go", true},
+		{"*", "dir/a.go", true},
+		{"*.go", "a.go", true},
+		{"*.go", "dir/sub/a.go", true},
+		{"*.go", "a.go.txt", false},
+		{"*.go", "a.go/b.txt", false},
+		{"/docs", "docs", true},
+		{"/docs", "docs/a.md", true},
+		{"/docs", "x/docs/a.md", false},
+		{"docs", "x/docs/a.md", true},
+		{"docs/", "docs/a.md", true},
+		{"docs/", "x/docs/a.md", true},
+		{"docs/", "docs", false},
+		{"docs/*", "docs/a.md", true},
+		{"docs/*", "docs/dir/a.md", false},
+		{"docs/*", "x/docs/a.md", false},
+		{"/build/logs/", "build/logs/a.log", true},
+		{"/build/logs/", "x/build/logs/a.log", false},
+		{"**/logs", "logs", true},
+		{"**/logs", "a/b/logs/c.log", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b/c", true},
+		{"a/**/b", "x/a/b", false},
+		{"lib/**", "lib/a/b.go", true},
+		{"lib/**", "lib", false},
+		{"foo?.txt", "foo1.txt", true},
+		{"foo?.txt", "foo/.txt", false},
+		{"a.b", "axb", false},
+		{`\#notes`, "#notes", true},
+		{".github/workflows/**", ".github/workflows/pkg/bot/bot.go", true},
+		{".github/workflows/**", "x/.github/workflows/a.yml", false},
+	}
+	for _, tt := range tests {
+		rule, err := Compile(tt.pattern)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.pattern, err)
+			continue
+		}
+		if got := rule.Match(tt.path); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, pattern := range []string{"!*.go", "[ab].go", "/"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	f := Parse([]byte(`# Comment
+*       @org/everyone
+
+/docs/  docs@example.com @alice # trailing comment
+\#tags  @bob
+!vendor @carol
+/vendor/
+`))
+	var got []string
+	for _, rule := range f.Rules {
+		got = append(got, rule.String())
+	}
+	want := []string{
+		"* @org/everyone",
+		"/docs/ docs@example.com @alice",
+		`\#tags @bob`,
+		"!vendor @carol",
+		"/vendor/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rules = %q, want %q", got, want)
+	}
+	if lines := []int{f.Rules[0].Line, f.Rules[1].Line, f.Rules[4].Line}; !reflect.DeepEqual(lines, []int{2, 4, 7}) {
+		t.Errorf("lines = %v, want [2 4 7]", lines)
+	}
+	if f.Rules[3].Err == nil {
+		t.Errorf("negated rule has no error")
+	}
+
+	tests := []struct {
+		path   string
+		owners []string
+	}{
+		{"main.go", []string{"@org/everyone"}},
+		{"docs/a.md", []string{"docs@example.com", "@alice"}},
+		{"#tags", []string{"@bob"}},
+		// The last matching rule wins, even without owners.
+		{"vendor/a.go", nil},
+		// Invalid rules never match.
+		{"x/vendor", []string{"@org/everyone"}},
+	}
+	for _, tt := range tests {
+		if got := f.Owners(tt.path); len(got) != len(tt.owners) || len(got) > 0 && !reflect.DeepEqual(got, tt.owners) {
+			t.Errorf("Owners(%q) = %q, want %q", tt.path, got, tt.owners)
+		}
+	}
+}
+
+func TestValidOwner(t *testing.T) {
+	tests := []struct {
+		owner string
+		valid bool
+	}{
+		{"@alice", true},
+		{"@org/team", true},
+		{"alice@example.com", true},
+		{"@org/", false},
+		{"@/team", false},
+		{"@org/a/b", false},
+		{"@", false},
+		{"alice", false},
+	}
+	for _, tt := range tests {
+		if err := ValidOwner(tt.owner); (err == nil) != tt.valid {
+			t.Errorf("ValidOwner(%q) = %v, want valid %v", tt.owner, err, tt.valid)
+		}
+	}
+}
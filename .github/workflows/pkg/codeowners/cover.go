@@ -0,0 +1,180 @@
+package codeowners
+
+import (
+	"sort"
+)
+
+// maxExactCandidates is the largest number of candidate owners for which
+// Cover searches for a minimal set exhaustively. Larger sets are covered
+// greedily.
+const maxExactCandidates = 16
+
+// Assignment is an owner chosen to review some of the changed paths.
+type Assignment struct {
+	// Owner is the owner as written in the CODEOWNERS file.
+	Owner string
+	// Paths are the changed paths the owner owns.
+	Paths []string
+	// Rules are the rules that made the owner an owner of Paths.
+	Rules []*Rule
+}
+
+// Cover returns the smallest set of owners such that every path owned by an
+// eligible owner has at least one of its owners in the set. Paths without
+// an eligible owner are returned as uncovered.
+func (f *File) Cover(paths []string, eligible func(owner string) bool) ([]Assignment, []string) {
+	var uncovered []string
+	// owners of each coverable path, restricted to eligible owners.
+	owners := make(map[string][]string)
+	rules := make(map[string]*Rule)
+	for _, path := range paths {
+		rule := f.Match(path)
+		var candidates []string
+		if rule != nil {
+			for _, owner := range rule.Owners {
+				if eligible(owner) {
+					candidates = append(candidates, owner)
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			uncovered = append(uncovered, path)
+			continue
+		}
+		owners[path] = candidates
+		rules[path] = rule
+	}
+
+	chosen := minimalCover(owners)
+	var assignments []Assignment
+	for _, owner := range chosen {
+		a := Assignment{Owner: owner}
+		seen := make(map[*Rule]bool)
+		for _, path := range paths {
+			if !contains(owners[path], owner) {
+				continue
+			}
+			a.Paths = append(a.Paths, path)
+			if rule := rules[path]; !seen[rule] {
+				seen[rule] = true
+				a.Rules = append(a.Rules, rule)
+			}
+		}
+		sort.Slice(a.Rules, func(i, j int) bool { return a.Rules[i].Line < a.Rules[j].Line })
+		assignments = append(assignments, a)
+	}
+	return assignments, uncovered
+}
+
+// minimalCover returns a smallest set of owners containing at least one
+// owner of every path. Ties are broken by owner name.
+func minimalCover(owners map[string][]string) []string {
+	// Paths with the same owners impose the same constraint.
+	constraints := make(map[string][]string)
+	candidateSet := make(map[string]bool)
+	for _, set := range owners {
+		sorted := append([]string(nil), set...)
+		sort.Strings(sorted)
+		key := ""
+		for _, owner := range sorted {
+			key += owner + "\x00"
+			candidateSet[owner] = true
+		}
+		constraints[key] = sorted
+	}
+	var candidates []string
+	for owner := range candidateSet {
+		candidates = append(candidates, owner)
+	}
+	sort.Strings(candidates)
+
+	satisfied := func(chosen []string) bool {
+		for _, set := range constraints {
+			ok := false
+			for _, owner := range set {
+				if contains(chosen, owner) {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	if len(candidates) <= maxExactCandidates {
+		for size := 0; size <= len(candidates); size++ {
+			if chosen := firstCombination(candidates, size, satisfied); chosen != nil {
+				return chosen
+			}
+		}
+	}
+	return greedyCover(candidates, constraints)
+}
+
+// firstCombination returns the first combination of size candidates, in
+// lexicographic order, for which ok returns true.
+func firstCombination(candidates []string, size int, ok func([]string) bool) []string {
+	chosen := make([]string, 0, size)
+	var search func(start int) []string
+	search = func(start int) []string {
+		if len(chosen) == size {
+			if ok(chosen) {
+				return append([]string(nil), chosen...)
+			}
+			return nil
+		}
+		for i := start; i <= len(candidates)-(size-len(chosen)); i++ {
+			chosen = append(chosen, candidates[i])
+			if result := search(i + 1); result != nil {
+				return result
+			}
+			chosen = chosen[:len(chosen)-1]
+		}
+		return nil
+	}
+	return search(0)
+}
+
+// greedyCover repeatedly picks the owner satisfying the most remaining
+// constraints.
+func greedyCover(candidates []string, constraints map[string][]string) []string {
+	remaining := make(map[string][]string, len(constraints))
+	for key, set := range constraints {
+		remaining[key] = set
+	}
+	var chosen []string
+	for len(remaining) > 0 {
+		best, bestCount := "", 0
+		for _, owner := range candidates {
+			count := 0
+			for _, set := range remaining {
+				if contains(set, owner) {
+					count++
+				}
+			}
+			if count > bestCount {
+				best, bestCount = owner, count
+			}
+		}
+		chosen = append(chosen, best)
+		for key, set := range remaining {
+			if contains(set, best) {
+				delete(remaining, key)
+			}
+		}
+	}
+	sort.Strings(chosen)
+	return chosen
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
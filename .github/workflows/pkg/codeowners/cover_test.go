@@ -0,0 +1,98 @@
+package codeowners
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCover(t *testing.T) {
+	f := Parse([]byte(`
+*.go        @alice @bob
+/api/       @carol @bob
+/docs/      @dave
+/vendor/
+/web/       @erin
+`))
+	tests := []struct {
+		name       string
+		paths      []string
+		ineligible []string
+		owners     []string
+		uncovered  []string
+	}{
+		{
+			name:   "ties broken by name",
+			paths:  []string{"main.go", "cmd/bot.go"},
+			owners: []string{"@alice"},
+		},
+		{
+			name:   "shared owner preferred",
+			paths:  []string{"main.go", "api/types.go"},
+			owners: []string{"@bob"},
+		},
+		{
+			name:   "disjoint owners",
+			paths:  []string{"main.go", "docs/a.md", "api/openapi.yaml"},
+			owners: []string{"@bob", "@dave"},
+		},
+		{
+			name:       "ineligible owner",
+			paths:      []string{"main.go", "api/openapi.yaml"},
+			ineligible: []string{"@bob"},
+			owners:     []string{"@alice", "@carol"},
+		},
+		{
+			name:       "unowned and ineligible paths",
+			paths:      []string{"vendor/a.go", "web/index.html", "README.md"},
+			ineligible: []string{"@erin"},
+			uncovered:  []string{"vendor/a.go", "web/index.html", "README.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignments, uncovered := f.Cover(tt.paths, func(owner string) bool { return !contains(tt.ineligible, owner) })
+			var owners []string
+			for _, a := range assignments {
+				owners = append(owners, a.Owner)
+			}
+			if !reflect.DeepEqual(owners, tt.owners) {
+				t.Errorf("owners = %v, want %v", owners, tt.owners)
+			}
+			if !reflect.DeepEqual(uncovered, tt.uncovered) {
+				t.Errorf("uncovered = %v, want %v", uncovered, tt.uncovered)
+			}
+		})
+	}
+}
+
+func TestCoverAssignment(t *testing.T) {
+	f := Parse([]byte("*.go @alice\n/api/ @alice @bob\n"))
+	assignments, _ := f.Cover([]string{"main.go", "api/a.go", "cmd/b.go"}, func(string) bool { return true })
+	if len(assignments) != 1 {
+		t.Fatalf("assignments = %v, want one", assignments)
+	}
+	a := assignments[0]
+	if want := []string{"main.go", "api/a.go", "cmd/b.go"}; a.Owner != "@alice" || !reflect.DeepEqual(a.Paths, want) {
+		t.Errorf("assignment = %v %v, want @alice %v", a.Owner, a.Paths, want)
+	}
+	if len(a.Rules) != 2 || a.Rules[0].Line != 1 || a.Rules[1].Line != 2 {
+		t.Errorf("rules = %v, want lines 1 and 2", a.Rules)
+	}
+}
+
+func TestCoverGreedy(t *testing.T) {
+	// More candidates than the exhaustive search handles: one owner per
+	// directory, plus one owning every directory.
+	var sb strings.Builder
+	var paths []string
+	for i := 0; i <= maxExactCandidates; i++ {
+		fmt.Fprintf(&sb, "/dir%v/ @owner%v @lead\n", i, i)
+		paths = append(paths, fmt.Sprintf("dir%v/a.go", i))
+	}
+	assignments, uncovered := Parse([]byte(sb.String())).Cover(paths, func(string) bool { return true })
+	if len(uncovered) != 0 || len(assignments) != 1 || assignments[0].Owner != "@lead" {
+		t.Errorf("Cover() = %v, %v, want only @lead", assignments, uncovered)
+	}
+}
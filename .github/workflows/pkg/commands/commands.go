@@ -0,0 +1,70 @@
+// Package commands parses the slash commands maintainers use in pull
+// request comments to steer the bot.
+package commands
+
+import (
+	"strings"
+)
+
+const (
+	// Assign requests reviews from the given users or teams.
+	Assign = "assign"
+	// Unassign removes review requests from the given users or teams.
+	Unassign = "unassign"
+	// Reroll replaces the pending review requests with other reviewers.
+	Reroll = "reroll"
+	// SkipReview approves the pull request without reviews. The rest of
+	// the line is the reason.
+	SkipReview = "skip-review"
+	// Recheck runs the approval check again.
+	Recheck = "recheck"
+)
+
+// Command is a slash command in a comment.
+type Command struct {
+	// Name is the command without the slash, e.g. "assign".
+	Name string
+	// Args are the whitespace separated arguments.
+	Args []string
+	// Text is the line the command was given on.
+	Text string
+}
+
+// Reason returns the arguments joined back into a sentence.
+func (c Command) Reason() string {
+	return strings.Join(c.Args, " ")
+}
+
+// known are the commands the bot understands.
+var known = map[string]bool{
+	Assign:     true,
+	Unassign:   true,
+	Reroll:     true,
+	SkipReview: true,
+	Recheck:    true,
+}
+
+// Parse returns the commands in a comment, one per line starting with a
+// known command. Lines in code blocks and quotes are ignored, so that
+// commands can be discussed without being run.
+func Parse(body string) []Command {
+	var cmds []Command
+	fenced := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || !strings.HasPrefix(line, "/") {
+			continue
+		}
+		fields := strings.Fields(line)
+		name := strings.TrimPrefix(fields[0], "/")
+		if !known[name] {
+			continue
+		}
+		cmds = append(cmds, Command{Name: name, Args: fields[1:], Text: line})
+	}
+	return cmds
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// Approvals configures the approvals a pull request needs.
+//
+//	approvals:
+//	  required: 2
+//	  max-age:
+//	    - branches: [master, "branch/v*"]
+//	      age: 336h
+type Approvals struct {
+	// Required is the number of approvals needed from the author's
+	// reviewers. Defaults to 1.
+	Required int `yaml:"required" json:"required"`
+	// MaxAge limits how long approvals count on pull requests into some
+	// branches. The first entry matching the base branch applies.
+	MaxAge []*MaxAge `yaml:"max-age" json:"max-age"`
+}
+
+// MaxAge is the longest an approval of a pull request into one of the
+// branches can precede the latest push and still count.
+type MaxAge struct {
+	// Branches are the patterns of the base branches.
+	Branches []string `yaml:"branches" json:"branches"`
+	// Age is the maximum age of an approval, e.g. 336h for two weeks.
+	Age time.Duration `yaml:"age" json:"age"`
+}
+
+// CheckAndSetDefaults validates the approval settings and fills in
+// defaults.
+func (a *Approvals) CheckAndSetDefaults() error {
+	if a.Required < 0 {
+		return fmt.Errorf("required must not be negative")
+	}
+	if a.Required == 0 {
+		a.Required = 1
+	}
+	for i, maxAge := range a.MaxAge {
+		if maxAge == nil {
+			return fmt.Errorf("max-age: entry %v is empty", i+1)
+		}
+		if len(maxAge.Branches) == 0 {
+			return fmt.Errorf("max-age: entry %v has no branches", i+1)
+		}
+		for _, pattern := range maxAge.Branches {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("max-age: %q: %w", pattern, err)
+			}
+		}
+		if maxAge.Age <= 0 {
+			return fmt.Errorf("max-age: entry %v must have a positive age", i+1)
+		}
+	}
+	return nil
+}
+
+// MaxAgeFor returns the maximum age of approvals on pull requests into a
+// branch, or zero if approvals do not expire.
+func (a *Approvals) MaxAgeFor(branch string) time.Duration {
+	for _, maxAge := range a.MaxAge {
+		for _, pattern := range maxAge.Branches {
+			if ok, _ := path.Match(pattern, branch); ok {
+				return maxAge.Age
+			}
+		}
+	}
+	return 0
+}
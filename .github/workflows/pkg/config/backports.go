@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// Backports configures how backports of reviewed pull requests to release
+// branches are reviewed.
+//
+//	backports:
+//	  branches: ["branch/v*"]
+//	  label: backport
+//	  approvals: 1
+//	  tolerance: 0.05
+type Backports struct {
+	// Branches are the patterns of the base branches of backports.
+	// Defaults to "branch/v*".
+	Branches []string `yaml:"branches" json:"branches"`
+	// Label marks pull requests as backports. Defaults to "backport".
+	Label string `yaml:"label" json:"label"`
+	// Approvals is the number of approvals a backport needs when its
+	// changes match the original's. Defaults to 1.
+	Approvals int `yaml:"approvals" json:"approvals"`
+	// Tolerance is the fraction of changed lines that may differ from the
+	// original. Defaults to 0, which requires identical patch IDs.
+	Tolerance float64 `yaml:"tolerance" json:"tolerance"`
+}
+
+// CheckAndSetDefaults validates the backport settings and fills in
+// defaults.
+func (b *Backports) CheckAndSetDefaults() error {
+	if len(b.Branches) == 0 {
+		b.Branches = []string{"branch/v*"}
+	}
+	for _, pattern := range b.Branches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("branches: %q: %w", pattern, err)
+		}
+	}
+	if b.Label == "" {
+		b.Label = "backport"
+	}
+	if b.Approvals < 0 {
+		return fmt.Errorf("approvals must not be negative")
+	}
+	if b.Approvals == 0 {
+		b.Approvals = 1
+	}
+	if b.Tolerance < 0 || b.Tolerance > 1 {
+		return fmt.Errorf("tolerance must be between 0 and 1")
+	}
+	return nil
+}
+
+// Branch returns true if a branch is a backport branch.
+func (b *Backports) Branch(branch string) bool {
+	for _, pattern := range b.Branches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+// Package config loads the review bot configuration from the repository.
+//
+// The configuration is always read from the default branch so that a pull
+// request cannot change the requirements it is checked against.
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/contributor"
+
+	"github.com/google/go-github/v37/github"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// Path is the location of the configuration file in the repository.
+	Path = ".github/review-bot.yaml"
+	// Version is the configuration schema version this bot understands.
+	Version = 1
+
+	// fallbackAuthor is the key of the reviewer set used for authors
+	// without their own entry.
+	fallbackAuthor = "*"
+)
+
+// Config is the review bot configuration.
+//
+//	version: 1
+//	groups:
+//	  core:
+//	    reviewers: [carol, dave, erin]
+//	    strategy: least-loaded
+//	    count: 2
+//	  security:
+//	    reviewers: ["@gravitational/security"]
+//	reviewers:
+//	  "*": [alice, bob]
+//	  alice: [bob, core]
+//	labels:
+//	  security: [core]
+//	teams:
+//	  request: members
+//	approvals:
+//	  required: 1
+//	  max-age:
+//	    - branches: [master]
+//	      age: 336h
+//	risk:
+//	  tiers:
+//	    - score: 20
+//	      reviewers: 2
+//	      approvals: 2
+//	expertise:
+//	  reviewers: 1
+//	backports:
+//	  branches: ["branch/v*"]
+//	dependencies:
+//	  go:
+//	    paths: [go.mod, go.sum, "vendor/**"]
+//	    manifests: [go.mod]
+//	    group: core
+//	    auto-approve: patch
+//	    checks: [vendor]
+//	two-person:
+//	  group: core
+//	drafts:
+//	  withdraw-reviews: true
+//	escalation:
+//	  remind: 24h
+//	  reassign: 72h
+//	bots: ["dependabot[bot]", "renovate[bot]"]
+type Config struct {
+	// Version is the schema version of the file, currently 1.
+	Version int `yaml:"version" json:"version"`
+	// Groups are named sets of reviewers that can be used in place of
+	// individual reviewers.
+	Groups map[string]*Group `yaml:"groups" json:"groups"`
+	// Reviewers maps pull request authors to their reviewers. The "*"
+	// entry is used for authors without their own entry. Entries naming a
+	// group stand for the group, and "@org/team" entries for the members
+	// of a GitHub team, including its child teams.
+	Reviewers Reviewers `yaml:"reviewers" json:"reviewers"`
+	// Labels maps pull request labels to the groups whose approval they
+	// require.
+	Labels Labels `yaml:"labels" json:"labels"`
+	// Teams configures how teams are requested.
+	Teams Teams `yaml:"teams" json:"teams"`
+	// Approvals configures how many approvals a pull request needs.
+	Approvals Approvals `yaml:"approvals" json:"approvals"`
+	// Risk configures how risky pull requests are reviewed.
+	Risk Risk `yaml:"risk" json:"risk"`
+	// Expertise configures requesting reviews from experts in the
+	// changed files.
+	Expertise Expertise `yaml:"expertise" json:"expertise"`
+	// Backports configures how backports are reviewed.
+	Backports Backports `yaml:"backports" json:"backports"`
+	// Dependencies configures how dependency updates by bots are
+	// reviewed, per ecosystem.
+	Dependencies Dependencies `yaml:"dependencies" json:"dependencies"`
+	// TwoPerson configures the two-person rule for sensitive paths.
+	TwoPerson TwoPerson `yaml:"two-person" json:"two-person"`
+	// Drafts configures how draft pull requests are handled.
+	Drafts Drafts `yaml:"drafts" json:"drafts"`
+	// Escalation configures reminders and reassignment of unanswered
+	// review requests.
+	Escalation Escalation `yaml:"escalation" json:"escalation"`
+	// Bots is the allowlist of GitHub App logins, e.g. "dependabot[bot]".
+	Bots []string `yaml:"bots" json:"bots"`
+}
+
+// CheckAndSetDefaults validates the configuration and fills in defaults.
+func (c *Config) CheckAndSetDefaults() error {
+	if c.Version != Version {
+		return fmt.Errorf("unsupported version %v, expected %v", c.Version, Version)
+	}
+	for name, group := range c.Groups {
+		if group == nil {
+			return fmt.Errorf("groups: %v is empty", name)
+		}
+		group.Name = name
+		if err := group.CheckAndSetDefaults(); err != nil {
+			return fmt.Errorf("groups: %v: %w", name, err)
+		}
+	}
+	for author, reviewers := range c.Reviewers {
+		if len(reviewers) == 0 {
+			return fmt.Errorf("reviewers: %v has no reviewers", author)
+		}
+		for _, reviewer := range reviewers {
+			if err := checkEntry(reviewer); err != nil {
+				return fmt.Errorf("reviewers: %v: %w", author, err)
+			}
+		}
+	}
+	if err := c.Labels.check(c.Groups); err != nil {
+		return fmt.Errorf("labels: %w", err)
+	}
+	if err := c.Teams.CheckAndSetDefaults(); err != nil {
+		return fmt.Errorf("teams: %w", err)
+	}
+	if err := c.Approvals.CheckAndSetDefaults(); err != nil {
+		return fmt.Errorf("approvals: %w", err)
+	}
+	if err := c.Risk.CheckAndSetDefaults(); err != nil {
+		return fmt.Errorf("risk: %w", err)
+	}
+	if err := c.Expertise.CheckAndSetDefaults(); err != nil {
+		return fmt.Errorf("expertise: %w", err)
+	}
+	if err := c.Backports.CheckAndSetDefaults(); err != nil {
+		return fmt.Errorf("backports: %w", err)
+	}
+	if err := c.Dependencies.check(c.Groups); err != nil {
+		return fmt.Errorf("dependencies: %w", err)
+	}
+	if err := c.TwoPerson.check(c.Groups); err != nil {
+		return fmt.Errorf("two-person: %w", err)
+	}
+	if err := c.Escalation.CheckAndSetDefaults(); err != nil {
+		return fmt.Errorf("escalation: %w", err)
+	}
+	if len(c.Bots) == 0 {
+		c.Bots = contributor.DefaultBots
+	}
+	for _, bot := range c.Bots {
+		if !strings.HasSuffix(bot, "[bot]") {
+			return fmt.Errorf("bots: %q is not a GitHub App login ending in [bot]", bot)
+		}
+	}
+	return nil
+}
+
+// Parse parses and validates a YAML configuration. Unknown fields are
+// rejected so that typos do not silently weaken requirements.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing %v: %w", Path, err)
+	}
+	if err := c.CheckAndSetDefaults(); err != nil {
+		return nil, fmt.Errorf("invalid %v: %w", Path, err)
+	}
+	return &c, nil
+}
+
+// Load reads the configuration from the default branch of a repository and
+// checks that its teams belong to the repository's owner. It returns nil if
+// the repository does not have a configuration file.
+func Load(ctx context.Context, client *github.Client, owner, repo string) (*Config, error) {
+	data, err := ReadFile(ctx, client, owner, repo, Path)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CheckOrganization(owner); err != nil {
+		return nil, fmt.Errorf("invalid %v: %w", Path, err)
+	}
+	return c, nil
+}
+
+// ReadFile reads a file from the default branch of a repository. It returns
+// nil if the file does not exist.
+func ReadFile(ctx context.Context, client *github.Client, owner, repo, path string) ([]byte, error) {
+	repository, _, err := client.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("fetching repository: %w", err)
+	}
+	branch := repository.GetDefaultBranch()
+	file, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{
+		Ref: branch,
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching %v from %v: %w", path, branch, err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("%v on %v is not a file", path, branch)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("decoding %v: %w", path, err)
+	}
+	return []byte(content), nil
+}
+
+// Reviewers maps pull request authors to the people who review their
+// changes.
+type Reviewers map[string][]string
+
+// ParseReviewers parses a JSON map of author to reviewers. An empty string
+// yields an empty map.
+func ParseReviewers(s string) (Reviewers, error) {
+	reviewers := Reviewers{}
+	if s == "" {
+		return reviewers, nil
+	}
+	if err := json.Unmarshal([]byte(s), &reviewers); err != nil {
+		return nil, fmt.Errorf("parsing reviewers: %w", err)
+	}
+	return reviewers, nil
+}
+
+// For returns the entries of an author's reviewer set, falling back to the
+// "*" entry. The author is never one of their own reviewers.
+func (r Reviewers) For(author string) []string {
+	set, ok := r[author]
+	if !ok {
+		set = r[fallbackAuthor]
+	}
+	var reviewers []string
+	for _, reviewer := range set {
+		if reviewer != author {
+			reviewers = append(reviewers, reviewer)
+		}
+	}
+	return reviewers
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+)
+
+const (
+	// AutoApproveNone never approves dependency updates automatically.
+	AutoApproveNone = "none"
+	// AutoApprovePatch approves patch level dependency updates
+	// automatically.
+	AutoApprovePatch = "patch"
+
+	// defaultCheckApp is the app whose check runs are trusted by default.
+	defaultCheckApp = "github-actions"
+)
+
+// Dependencies configures how pull requests by bots that update
+// dependencies are reviewed, per ecosystem.
+//
+//	dependencies:
+//	  go:
+//	    paths: [go.mod, go.sum, "vendor/**"]
+//	    manifests: [go.mod]
+//	    group: deps
+//	    auto-approve: patch
+//	    checks: [vendor]
+//	    check-app: github-actions
+type Dependencies map[string]*Ecosystem
+
+// Ecosystem is the review policy of dependency updates of one ecosystem.
+type Ecosystem struct {
+	// Name is the key of the ecosystem in the configuration.
+	Name string `yaml:"-" json:"name"`
+	// Paths are CODEOWNERS style patterns of the files an update of the
+	// ecosystem changes. A bot pull request belongs to the ecosystem if it
+	// only changes these files.
+	Paths []string `yaml:"paths" json:"paths"`
+	// Manifests are patterns of the files dependency versions are read
+	// from, e.g. go.mod.
+	Manifests []string `yaml:"manifests" json:"manifests"`
+	// Group is the reviewer group that reviews the updates instead of
+	// the reviewers of the bot.
+	Group string `yaml:"group" json:"group"`
+	// AutoApprove is "none" (the default) or "patch" to approve updates
+	// that only change patch versions. Approving automatically requires
+	// manifests and checks.
+	AutoApprove string `yaml:"auto-approve" json:"auto-approve"`
+	// Checks are the check runs, e.g. a vendor consistency check, that
+	// must pass before an update is approved.
+	Checks []string `yaml:"checks" json:"checks"`
+	// CheckApp is the slug of the app that must have created the check
+	// runs, so that other apps cannot pass them. Defaults to
+	// github-actions.
+	CheckApp string `yaml:"check-app" json:"check-app"`
+
+	paths     []*codeowners.Rule
+	manifests []*codeowners.Rule
+}
+
+// check validates the ecosystems and fills in defaults.
+func (d Dependencies) check(groups map[string]*Group) error {
+	for name, e := range d {
+		if e == nil {
+			return fmt.Errorf("%v is empty", name)
+		}
+		e.Name = name
+		if err := e.CheckAndSetDefaults(groups); err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// CheckAndSetDefaults validates the ecosystem and fills in defaults.
+func (e *Ecosystem) CheckAndSetDefaults(groups map[string]*Group) error {
+	if len(e.Paths) == 0 {
+		return fmt.Errorf("no paths")
+	}
+	var err error
+	if e.paths, err = compileAll(e.Paths); err != nil {
+		return fmt.Errorf("paths: %w", err)
+	}
+	if e.manifests, err = compileAll(e.Manifests); err != nil {
+		return fmt.Errorf("manifests: %w", err)
+	}
+	if _, ok := groups[e.Group]; !ok {
+		return fmt.Errorf("unknown group %q", e.Group)
+	}
+	if e.CheckApp == "" {
+		e.CheckApp = defaultCheckApp
+	}
+	switch e.AutoApprove {
+	case "":
+		e.AutoApprove = AutoApproveNone
+	case AutoApproveNone:
+	case AutoApprovePatch:
+		if len(e.Manifests) == 0 {
+			return fmt.Errorf("auto-approve %q needs manifests to read versions from", e.AutoApprove)
+		}
+		if len(e.Checks) == 0 {
+			return fmt.Errorf("auto-approve %q needs checks that must pass first", e.AutoApprove)
+		}
+	default:
+		return fmt.Errorf("unknown auto-approve %q, expected %q or %q", e.AutoApprove, AutoApproveNone, AutoApprovePatch)
+	}
+	return nil
+}
+
+// Owns returns true if a path belongs to the ecosystem.
+func (e *Ecosystem) Owns(path string) bool {
+	return matchAny(e.paths, path)
+}
+
+// Manifest returns true if a path is a manifest of the ecosystem.
+func (e *Ecosystem) Manifest(path string) bool {
+	return matchAny(e.manifests, path)
+}
+
+// EcosystemFor returns the first ecosystem, by name, owning every path, or
+// nil if there is none.
+func (c *Config) EcosystemFor(paths []string) *Ecosystem {
+	names := make([]string, 0, len(c.Dependencies))
+	for name := range c.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e := c.Dependencies[name]
+		owned := len(paths) > 0
+		for _, path := range paths {
+			owned = owned && e.Owns(path)
+		}
+		if owned {
+			return e
+		}
+	}
+	return nil
+}
+
+func compileAll(patterns []string) ([]*codeowners.Rule, error) {
+	var rules []*codeowners.Rule
+	for _, pattern := range patterns {
+		rule, err := codeowners.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func matchAny(rules []*codeowners.Rule, path string) bool {
+	for _, rule := range rules {
+		if rule.Match(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package config
+
+// Drafts configures how draft pull requests are handled. Reviewers are
+// never assigned to drafts.
+//
+//	drafts:
+//	  withdraw-reviews: true
+type Drafts struct {
+	// WithdrawReviews withdraws pending review requests when a pull
+	// request is converted back to a draft.
+	WithdrawReviews bool `yaml:"withdraw-reviews" json:"withdraw-reviews"`
+}
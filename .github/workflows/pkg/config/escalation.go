@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// defaultRemind is how long a review request goes unanswered before
+	// the reviewer is reminded, by default.
+	defaultRemind = 48 * time.Hour
+	// defaultReassign is how long a review request goes unanswered before
+	// the reviewer is replaced, by default.
+	defaultReassign = 96 * time.Hour
+)
+
+// Escalation configures what happens to review requests that go
+// unanswered.
+//
+//	escalation:
+//	  remind: 24h
+//	  reassign: 72h
+type Escalation struct {
+	// Remind is how long a review request can go unanswered before the
+	// reviewer is reminded. Defaults to 48h.
+	Remind time.Duration `yaml:"remind" json:"remind"`
+	// Reassign is how long a review request can go unanswered before the
+	// reviewer is replaced by a backup from the same group. It must be
+	// longer than Remind. Defaults to 96h.
+	Reassign time.Duration `yaml:"reassign" json:"reassign"`
+}
+
+// CheckAndSetDefaults validates the escalation settings and fills in
+// defaults.
+func (e *Escalation) CheckAndSetDefaults() error {
+	if e.Remind < 0 || e.Reassign < 0 {
+		return fmt.Errorf("durations must not be negative")
+	}
+	if e.Remind == 0 {
+		e.Remind = defaultRemind
+	}
+	if e.Reassign == 0 {
+		e.Reassign = defaultReassign
+	}
+	if e.Reassign <= e.Remind {
+		return fmt.Errorf("reassign (%v) must be longer than remind (%v)", e.Reassign, e.Remind)
+	}
+	return nil
+}
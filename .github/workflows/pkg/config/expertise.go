@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultHistory is how far back commit history is considered, by default.
+const defaultHistory = 365 * 24 * time.Hour
+
+// Expertise configures requesting reviews from the people who have worked
+// on the changed files most.
+//
+//	expertise:
+//	  reviewers: 1
+//	  history: 8760h
+type Expertise struct {
+	// Reviewers is the number of experts from the author's reviewers that
+	// are requested. Defaults to 0, which disables expert suggestions.
+	Reviewers int `yaml:"reviewers" json:"reviewers"`
+	// History is how far back commits are considered. Defaults to a year.
+	History time.Duration `yaml:"history" json:"history"`
+}
+
+// CheckAndSetDefaults validates the expertise settings and fills in
+// defaults.
+func (e *Expertise) CheckAndSetDefaults() error {
+	if e.Reviewers < 0 || e.History < 0 {
+		return fmt.Errorf("values must not be negative")
+	}
+	if e.History == 0 {
+		e.History = defaultHistory
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// StrategyAll requests a review from every reviewer in a group.
+	StrategyAll = "all"
+	// StrategyLeastLoaded requests reviews from the reviewers in a group
+	// with the fewest outstanding review requests.
+	StrategyLeastLoaded = "least-loaded"
+)
+
+// Group is a named set of reviewers and how to choose among them.
+type Group struct {
+	// Name is the key of the group in the configuration.
+	Name string `yaml:"-" json:"name"`
+	// Reviewers are the members of the group. "@org/team" entries stand
+	// for the members of a GitHub team.
+	Reviewers []string `yaml:"reviewers" json:"reviewers"`
+	// Strategy is how reviewers are chosen: "all" (the default) or
+	// "least-loaded".
+	Strategy string `yaml:"strategy" json:"strategy"`
+	// Count is the number of reviewers the least-loaded strategy chooses.
+	// Defaults to 1.
+	Count int `yaml:"count" json:"count"`
+}
+
+// CheckAndSetDefaults validates the group and fills in defaults.
+func (g *Group) CheckAndSetDefaults() error {
+	if len(g.Reviewers) == 0 {
+		return fmt.Errorf("no reviewers")
+	}
+	teams := false
+	for _, reviewer := range g.Reviewers {
+		if err := checkEntry(reviewer); err != nil {
+			return err
+		}
+		teams = teams || IsTeam(reviewer)
+	}
+	switch g.Strategy {
+	case "":
+		g.Strategy = StrategyAll
+	case StrategyAll, StrategyLeastLoaded:
+	default:
+		return fmt.Errorf("unknown strategy %q, expected %q or %q", g.Strategy, StrategyAll, StrategyLeastLoaded)
+	}
+	if g.Count < 0 {
+		return fmt.Errorf("count must not be negative")
+	}
+	if g.Count == 0 {
+		g.Count = 1
+	}
+	// The size of a group with teams is only known once the teams are
+	// resolved.
+	if g.Strategy == StrategyLeastLoaded && !teams && g.Count > len(g.Reviewers) {
+		return fmt.Errorf("count %v is larger than the group", g.Count)
+	}
+	return nil
+}
+
+// GroupsFor returns the reviewer groups of an author's pull requests. The
+// author is removed from every group. Individual reviewers in the author's
+// reviewer set are returned as an unnamed group requested in full.
+func (c *Config) GroupsFor(author string) []Group {
+	var groups []Group
+	individuals := Group{Strategy: StrategyAll}
+	for _, entry := range c.Reviewers.For(author) {
+		group, ok := c.Groups[entry]
+		if !ok {
+			individuals.Reviewers = append(individuals.Reviewers, entry)
+			continue
+		}
+		groups = append(groups, withoutAuthor(group, author))
+	}
+	if len(individuals.Reviewers) > 0 {
+		groups = append([]Group{individuals}, groups...)
+	}
+	return groups
+}
+
+// GroupFor returns a named group without the author, and false if there is
+// no such group.
+func (c *Config) GroupFor(name, author string) (Group, bool) {
+	group, ok := c.Groups[name]
+	if !ok {
+		return Group{}, false
+	}
+	return withoutAuthor(group, author), true
+}
+
+// withoutAuthor returns a copy of a group without the author.
+func withoutAuthor(group *Group, author string) Group {
+	g := *group
+	g.Reviewers = nil
+	for _, reviewer := range group.Reviewers {
+		if reviewer != author {
+			g.Reviewers = append(g.Reviewers, reviewer)
+		}
+	}
+	return g
+}
+
+// ReviewersFor returns everybody who can review an author's pull requests,
+// with groups expanded. Teams are not expanded.
+func (c *Config) ReviewersFor(author string) []string {
+	var reviewers []string
+	seen := make(map[string]bool)
+	for _, group := range c.GroupsFor(author) {
+		for _, reviewer := range group.Reviewers {
+			if !seen[reviewer] {
+				seen[reviewer] = true
+				reviewers = append(reviewers, reviewer)
+			}
+		}
+	}
+	return reviewers
+}
+
+// AllReviewers returns every reviewer entry in the configuration, sorted,
+// with groups expanded. Teams are not expanded.
+func (c *Config) AllReviewers() []string {
+	seen := make(map[string]bool)
+	for _, group := range c.Groups {
+		for _, reviewer := range group.Reviewers {
+			seen[reviewer] = true
+		}
+	}
+	for _, entries := range c.Reviewers {
+		for _, entry := range entries {
+			if _, ok := c.Groups[entry]; !ok {
+				seen[entry] = true
+			}
+		}
+	}
+	reviewers := make([]string, 0, len(seen))
+	for reviewer := range seen {
+		reviewers = append(reviewers, reviewer)
+	}
+	sort.Strings(reviewers)
+	return reviewers
+}
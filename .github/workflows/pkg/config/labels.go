@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Labels maps pull request labels to the reviewer groups they pull in.
+//
+//	labels:
+//	  security: [security]
+//	  docs: [docs]
+type Labels map[string][]string
+
+// check validates that every label names existing groups.
+func (l Labels) check(groups map[string]*Group) error {
+	for label, names := range l {
+		if len(names) == 0 {
+			return fmt.Errorf("%v has no groups", label)
+		}
+		for _, name := range names {
+			if _, ok := groups[name]; !ok {
+				return fmt.Errorf("%v: unknown group %q", label, name)
+			}
+		}
+	}
+	return nil
+}
+
+// LabelGroup is a reviewer group pulled in by a label.
+type LabelGroup struct {
+	// Label is the label that pulled in the group.
+	Label string
+	// Group is the group, without the pull request author.
+	Group Group
+}
+
+// GroupsForLabels returns the reviewer groups pulled in by the labels of an
+// author's pull request, ordered by label. The author is removed from every
+// group.
+func (c *Config) GroupsForLabels(labels []string, author string) []LabelGroup {
+	sorted := append([]string(nil), labels...)
+	sort.Strings(sorted)
+	var groups []LabelGroup
+	for _, label := range sorted {
+		for _, name := range c.Labels[label] {
+			groups = append(groups, LabelGroup{
+				Label: label,
+				Group: withoutAuthor(c.Groups[name], author),
+			})
+		}
+	}
+	return groups
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+)
+
+// defaultSensitive are the paths whose changes are sensitive by default.
+var defaultSensitive = []string{".github/workflows/**", "vendor/**"}
+
+// Risk configures how the risk score of a pull request changes its review
+// requirements.
+//
+//	risk:
+//	  sensitive: [".github/workflows/**", "vendor/**", "lib/auth/**"]
+//	  tiers:
+//	    - score: 5
+//	      reviewers: 2
+//	      approvals: 2
+//	    - score: 20
+//	      reviewers: 3
+//	      approvals: 2
+type Risk struct {
+	// Sensitive are CODEOWNERS style patterns of paths whose changes add
+	// to the risk score. Defaults to workflows and vendored code.
+	Sensitive []string `yaml:"sensitive" json:"sensitive"`
+	// Tiers raise the number of reviewers and approvals of pull requests
+	// from a risk score upwards. Without tiers the score is only reported.
+	Tiers []Tier `yaml:"tiers" json:"tiers"`
+
+	sensitive []*codeowners.Rule
+}
+
+// Tier is the review requirements of pull requests with at least a given
+// risk score.
+type Tier struct {
+	// Score is the lowest risk score the tier applies to.
+	Score int `yaml:"score" json:"score"`
+	// Reviewers is the minimum number of reviewers requested.
+	Reviewers int `yaml:"reviewers" json:"reviewers"`
+	// Approvals is the minimum number of approvals required.
+	Approvals int `yaml:"approvals" json:"approvals"`
+}
+
+// CheckAndSetDefaults validates the risk settings and fills in defaults.
+func (r *Risk) CheckAndSetDefaults() error {
+	if len(r.Sensitive) == 0 {
+		r.Sensitive = defaultSensitive
+	}
+	var err error
+	if r.sensitive, err = compileAll(r.Sensitive); err != nil {
+		return fmt.Errorf("sensitive: %w", err)
+	}
+	for i, tier := range r.Tiers {
+		if tier.Score < 0 || tier.Reviewers < 0 || tier.Approvals < 0 {
+			return fmt.Errorf("tiers: %v: values must not be negative", i)
+		}
+	}
+	sort.SliceStable(r.Tiers, func(i, j int) bool { return r.Tiers[i].Score < r.Tiers[j].Score })
+	return nil
+}
+
+// SensitiveRules returns the compiled sensitive path patterns.
+func (r *Risk) SensitiveRules() []*codeowners.Rule {
+	return r.sensitive
+}
+
+// TierFor returns the tier with the highest score not above a risk score,
+// or an empty tier if there is none.
+func (r *Risk) TierFor(score int) Tier {
+	var tier Tier
+	for _, t := range r.Tiers {
+		if t.Score <= score {
+			tier = t
+		}
+	}
+	return tier
+}
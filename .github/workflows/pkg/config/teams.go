@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// TeamRequestTeam requests a review from a team itself, leaving the
+	// choice of reviewer to GitHub's team review assignment.
+	TeamRequestTeam = "team"
+	// TeamRequestMembers requests reviews from the members of a team
+	// individually, chosen by the group's strategy.
+	TeamRequestMembers = "members"
+)
+
+// Teams configures how "@org/team" entries in reviewer sets and groups are
+// requested. Approval from any member of a team counts as approval from
+// the team either way.
+type Teams struct {
+	// Request is "team" (the default) to request a review from the team or
+	// "members" to request reviews from its members.
+	Request string `yaml:"request" json:"request"`
+}
+
+// CheckAndSetDefaults validates the team settings and fills in defaults.
+func (t *Teams) CheckAndSetDefaults() error {
+	switch t.Request {
+	case "":
+		t.Request = TeamRequestTeam
+	case TeamRequestTeam, TeamRequestMembers:
+	default:
+		return fmt.Errorf("unknown team request %q, expected %q or %q", t.Request, TeamRequestTeam, TeamRequestMembers)
+	}
+	return nil
+}
+
+// IsTeam returns true if a reviewer entry names a team, e.g.
+// "@gravitational/core".
+func IsTeam(entry string) bool {
+	return strings.HasPrefix(entry, "@")
+}
+
+// ParseTeam splits a team entry into its organization and slug.
+func ParseTeam(entry string) (org, slug string, err error) {
+	parts := strings.Split(strings.TrimPrefix(entry, "@"), "/")
+	if !IsTeam(entry) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid team %q, expected @org/team", entry)
+	}
+	return parts[0], parts[1], nil
+}
+
+// CheckOrganization returns an error if a reviewer set or group names a
+// team of an organization other than org. Reviews can only be requested
+// from teams of the repository's organization.
+func (c *Config) CheckOrganization(org string) error {
+	authors := make([]string, 0, len(c.Reviewers))
+	for author := range c.Reviewers {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+	for _, author := range authors {
+		if err := checkTeamOrganization(c.Reviewers[author], org); err != nil {
+			return fmt.Errorf("reviewers: %v: %w", author, err)
+		}
+	}
+	names := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checkTeamOrganization(c.Groups[name].Reviewers, org); err != nil {
+			return fmt.Errorf("groups: %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkTeamOrganization returns an error if any of the entries is a team of
+// an organization other than org.
+func checkTeamOrganization(entries []string, org string) error {
+	for _, entry := range entries {
+		if !IsTeam(entry) {
+			continue
+		}
+		teamOrg, _, err := ParseTeam(entry)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(teamOrg, org) {
+			return fmt.Errorf("team %v is not in organization %v", entry, org)
+		}
+	}
+	return nil
+}
+
+// checkEntry validates a reviewer entry.
+func checkEntry(entry string) error {
+	if entry == "" {
+		return fmt.Errorf("empty reviewer")
+	}
+	if IsTeam(entry) {
+		_, _, err := ParseTeam(entry)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestCheckOrganization(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Config
+		ok   bool
+	}{
+		{
+			name: "users only",
+			c:    Config{Reviewers: Reviewers{"alice": {"bob"}}, Groups: map[string]*Group{"core": {Reviewers: []string{"carol"}}}},
+			ok:   true,
+		},
+		{
+			name: "teams of the organization",
+			c:    Config{Reviewers: Reviewers{"alice": {"@gravitational/core"}}, Groups: map[string]*Group{"core": {Reviewers: []string{"@Gravitational/security"}}}},
+			ok:   true,
+		},
+		{
+			name: "reviewer set with a team of another organization",
+			c:    Config{Reviewers: Reviewers{"alice": {"bob", "@other/core"}}},
+		},
+		{
+			name: "group with a team of another organization",
+			c:    Config{Groups: map[string]*Group{"core": {Reviewers: []string{"@other/core"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.c.CheckOrganization("gravitational")
+			if (err == nil) != tt.ok {
+				t.Errorf("CheckOrganization() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
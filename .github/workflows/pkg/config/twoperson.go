@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/availability"
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+)
+
+// defaultTwoPersonPaths are the paths the two-person rule covers by
+// default: the workflows, including the bot's own code, CODEOWNERS and the
+// files deciding who must approve, so that one person cannot weaken the
+// policy itself.
+var defaultTwoPersonPaths = []string{".github/workflows/**", "CODEOWNERS", "/" + Path, "/" + availability.Path}
+
+// TwoPerson configures the two-person rule: changes to sensitive paths need
+// approvals from distinct members of a security group, none of whom
+// authored or co-authored a commit in the pull request.
+//
+//	two-person:
+//	  group: security
+//	  paths: [".github/workflows/**", "CODEOWNERS"]
+type TwoPerson struct {
+	// Group is the security group whose members approve. It is required.
+	Group string `yaml:"group" json:"group"`
+	// Paths are CODEOWNERS style patterns of the sensitive paths. Defaults
+	// to the workflows, CODEOWNERS, this configuration and the reviewer
+	// availability calendar.
+	Paths []string `yaml:"paths" json:"paths"`
+	// Approvals is the number of distinct approvers. Defaults to 2, and
+	// cannot be lower.
+	Approvals int `yaml:"approvals" json:"approvals"`
+
+	paths []*codeowners.Rule
+}
+
+// check validates the rule and fills in defaults.
+func (t *TwoPerson) check(groups map[string]*Group) error {
+	if t.Group == "" {
+		return fmt.Errorf("group is required")
+	}
+	if _, ok := groups[t.Group]; !ok {
+		return fmt.Errorf("unknown group %q", t.Group)
+	}
+	if len(t.Paths) == 0 {
+		t.Paths = defaultTwoPersonPaths
+	}
+	var err error
+	if t.paths, err = compileAll(t.Paths); err != nil {
+		return fmt.Errorf("paths: %w", err)
+	}
+	if t.Approvals == 0 {
+		t.Approvals = 2
+	}
+	if t.Approvals < 2 {
+		return fmt.Errorf("approvals must be at least 2")
+	}
+	return nil
+}
+
+// Sensitive returns the paths the rule covers.
+func (t *TwoPerson) Sensitive(paths []string) []string {
+	var sensitive []string
+	for _, path := range paths {
+		if matchAny(t.paths, path) {
+			sensitive = append(sensitive, path)
+		}
+	}
+	return sensitive
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTwoPerson(t *testing.T) {
+	groups := map[string]*Group{"security": {Name: "security", Reviewers: []string{"alice", "bob"}}}
+
+	var missing TwoPerson
+	if err := missing.check(groups); err == nil {
+		t.Errorf("check() without a group succeeded")
+	}
+	unknown := TwoPerson{Group: "core"}
+	if err := unknown.check(groups); err == nil {
+		t.Errorf("check() with an unknown group succeeded")
+	}
+
+	rule := TwoPerson{Group: "security"}
+	if err := rule.check(groups); err != nil {
+		t.Fatalf("check(): %v", err)
+	}
+	if rule.Approvals != 2 {
+		t.Errorf("Approvals = %v, want 2", rule.Approvals)
+	}
+	paths := []string{
+		".github/workflows/check.yml",
+		".github/workflows/pkg/bot/bot.go",
+		".github/review-bot.yaml",
+		".github/reviewer-availability.yaml",
+		"CODEOWNERS",
+		"docs/CODEOWNERS",
+		"lib/review-bot.yaml",
+		"main.go",
+	}
+	want := []string{
+		".github/workflows/check.yml",
+		".github/workflows/pkg/bot/bot.go",
+		".github/review-bot.yaml",
+		".github/reviewer-availability.yaml",
+		"CODEOWNERS",
+		"docs/CODEOWNERS",
+	}
+	if got := rule.Sensitive(paths); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sensitive() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package contributor
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+var (
+	// coAuthorTrailer matches a "Co-authored-by: Name <email>" trailer.
+	coAuthorTrailer = regexp.MustCompile(`(?i)^co-authored-by:\s*(.*?)\s*<([^>]+)>\s*$`)
+	// noReplyEmail matches GitHub's private commit emails,
+	// "login@users.noreply.github.com" or
+	// "123+login@users.noreply.github.com".
+	noReplyEmail = regexp.MustCompile(`(?i)^(?:\d+\+)?([^@+]+)@users\.noreply\.github\.com$`)
+)
+
+// CoAuthor is a person credited in a Co-authored-by trailer.
+type CoAuthor struct {
+	Name  string
+	Email string
+}
+
+// CoAuthors returns the co-authors credited in a commit message.
+func CoAuthors(message string) []CoAuthor {
+	var coAuthors []CoAuthor
+	scanner := bufio.NewScanner(strings.NewReader(message))
+	for scanner.Scan() {
+		if m := coAuthorTrailer.FindStringSubmatch(strings.TrimSpace(scanner.Text())); m != nil {
+			coAuthors = append(coAuthors, CoAuthor{Name: m[1], Email: strings.ToLower(m[2])})
+		}
+	}
+	return coAuthors
+}
+
+// NoReplyLogin returns the login of a GitHub private commit email.
+func NoReplyLogin(email string) (string, bool) {
+	m := noReplyEmail.FindStringSubmatch(email)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
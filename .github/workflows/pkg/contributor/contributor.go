@@ -0,0 +1,121 @@
+// Package contributor classifies the authors of pull requests as internal
+// contributors, external contributors or bots.
+package contributor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v37/github"
+)
+
+// Classification is the kind of contributor a user is.
+type Classification string
+
+const (
+	// Internal contributors are members of the organization or
+	// collaborators with write access to the repository.
+	Internal Classification = "internal"
+	// External contributors are everybody else.
+	External Classification = "external"
+	// Bot contributors are GitHub App accounts on the configured
+	// allowlist.
+	Bot Classification = "bot"
+)
+
+// DefaultBots are the accounts classified as bots when no allowlist is
+// configured.
+var DefaultBots = []string{"dependabot[bot]", "renovate[bot]"}
+
+// Config configures a Classifier.
+type Config struct {
+	// GitHub is the API client.
+	GitHub *github.Client
+	// Organization is the organization internal contributors belong to.
+	Organization string
+	// Repository is the repository collaborator permissions are checked on.
+	Repository string
+	// Bots is the allowlist of GitHub App logins, e.g. "dependabot[bot]".
+	Bots []string
+}
+
+// Classifier classifies contributors, caching the result for each user.
+type Classifier struct {
+	c     Config
+	cache map[string]Classification
+}
+
+// NewClassifier returns a classifier for the given configuration.
+func NewClassifier(c Config) *Classifier {
+	if c.Bots == nil {
+		c.Bots = DefaultBots
+	}
+	return &Classifier{
+		c:     c,
+		cache: make(map[string]Classification),
+	}
+}
+
+// Classify returns the classification of a user given its login, account
+// type and author association. The author association of the user with the
+// repository, as reported in event payloads, is checked before making API
+// calls.
+func (c *Classifier) Classify(ctx context.Context, login, accountType, association string) (Classification, error) {
+	if classification, ok := c.cache[login]; ok {
+		return classification, nil
+	}
+	classification, err := c.classify(ctx, login, accountType, association)
+	if err != nil {
+		return "", err
+	}
+	c.cache[login] = classification
+	return classification, nil
+}
+
+func (c *Classifier) classify(ctx context.Context, login, accountType, association string) (Classification, error) {
+	if c.isBot(login, accountType) {
+		return Bot, nil
+	}
+	switch association {
+	case "OWNER", "MEMBER":
+		return Internal, nil
+	}
+
+	member, _, err := c.c.GitHub.Organizations.IsMember(ctx, c.c.Organization, login)
+	if err != nil {
+		return "", fmt.Errorf("checking membership of %v: %w", login, err)
+	}
+	if member {
+		return Internal, nil
+	}
+
+	level, resp, err := c.c.GitHub.Repositories.GetPermissionLevel(ctx, c.c.Organization, c.c.Repository, login)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return External, nil
+		}
+		return "", fmt.Errorf("checking permission of %v: %w", login, err)
+	}
+	switch level.GetPermission() {
+	case "admin", "write":
+		return Internal, nil
+	}
+	return External, nil
+}
+
+// isBot returns true if a GitHub App account is on the bot allowlist. The
+// login must match an entry exactly, "[bot]" suffix included, so that user
+// accounts named after an app are not mistaken for it.
+func (c *Classifier) isBot(login, accountType string) bool {
+	if accountType != "Bot" {
+		return false
+	}
+	for _, bot := range c.c.Bots {
+		if strings.EqualFold(bot, login) {
+			return true
+		}
+	}
+	return false
+}
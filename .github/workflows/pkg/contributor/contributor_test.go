@@ -0,0 +1,95 @@
+package contributor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v37/github"
+)
+
+func TestClassifyBots(t *testing.T) {
+	tests := []struct {
+		name        string
+		login       string
+		accountType string
+		bot         bool
+	}{
+		{name: "app", login: "dependabot[bot]", accountType: "Bot", bot: true},
+		{name: "app in other case", login: "Renovate[bot]", accountType: "Bot", bot: true},
+		{name: "user named after an app", login: "dependabot", accountType: "User"},
+		{name: "app without suffix", login: "dependabot", accountType: "Bot"},
+		{name: "user with suffix", login: "dependabot[bot]", accountType: "User"},
+		{name: "other app", login: "github-actions[bot]", accountType: "Bot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClassifier(Config{})
+			if got := c.isBot(tt.login, tt.accountType); got != tt.bot {
+				t.Errorf("isBot(%q, %q) = %v, want %v", tt.login, tt.accountType, got, tt.bot)
+			}
+		})
+	}
+
+	// Bots are classified without API calls.
+	c := NewClassifier(Config{})
+	classification, err := c.Classify(context.Background(), "dependabot[bot]", "Bot", "NONE")
+	if err != nil || classification != Bot {
+		t.Errorf("Classify() = %v, %v, want %v", classification, err, Bot)
+	}
+}
+
+func TestClassifyCollaborators(t *testing.T) {
+	permissions := map[string]string{
+		"writer":     "write",
+		"maintainer": "admin",
+		"reader":     "read",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/acme/members/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/orgs/acme/members/member" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/repos/acme/widgets/collaborators/", func(w http.ResponseWriter, r *http.Request) {
+		login := r.URL.Path[len("/repos/acme/widgets/collaborators/") : len(r.URL.Path)-len("/permission")]
+		permission, ok := permissions[login]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"permission": %q}`, permission)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	tests := []struct {
+		login string
+		want  Classification
+	}{
+		{login: "member", want: Internal},
+		{login: "writer", want: Internal},
+		{login: "maintainer", want: Internal},
+		{login: "reader", want: External},
+		{login: "stranger", want: External},
+	}
+	for _, tt := range tests {
+		t.Run(tt.login, func(t *testing.T) {
+			c := NewClassifier(Config{GitHub: client, Organization: "acme", Repository: "widgets"})
+			got, err := c.Classify(context.Background(), tt.login, "User", "CONTRIBUTOR")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Classify(%q) = %v, want %v", tt.login, got, tt.want)
+			}
+		})
+	}
+}
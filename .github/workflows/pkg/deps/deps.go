@@ -0,0 +1,136 @@
+// Package deps extracts dependency version changes from the diffs of
+// dependency manifests, such as go.mod or package.json.
+package deps
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Major is a change of the major version.
+	Major = "major"
+	// Minor is a change of the minor version.
+	Minor = "minor"
+	// Patch is a change of the patch version only.
+	Patch = "patch"
+	// Unknown is a dependency that was added, removed or whose versions
+	// could not be compared.
+	Unknown = "unknown"
+)
+
+// requirement matches a whole added or removed diff line requiring a
+// dependency at a semantic version, e.g. "+\tgithub.com/a/b v1.2.3 // indirect"
+// or `-    "lodash": "^4.17.20",`. Anything else on the line, such as a
+// replace directive, does not match.
+var requirement = regexp.MustCompile(`^([+-])\s*(?:require\s+)?"?([^\s":=<>]+)"?\s*:?\s*"?[~^]?v?(\d+)\.(\d+)\.(\d+)((?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)"?,?\s*(?://\s*indirect)?$`)
+
+// Change is a change of the version of a dependency.
+type Change struct {
+	// Name is the name of the dependency.
+	Name string
+	// From is the previous version, empty if the dependency was added.
+	From string
+	// To is the new version, empty if the dependency was removed.
+	To string
+
+	from, to []int
+	// prerelease is true if either version has a pre-release or build
+	// suffix, e.g. a Go pseudo-version of an arbitrary commit.
+	prerelease bool
+}
+
+// Level returns how significant the change is: major, minor, patch or
+// unknown. Changes from or to pre-release versions are unknown.
+func (c Change) Level() string {
+	if c.from == nil || c.to == nil || c.prerelease {
+		return Unknown
+	}
+	switch {
+	case c.from[0] != c.to[0]:
+		return Major
+	case c.from[1] != c.to[1]:
+		return Minor
+	default:
+		return Patch
+	}
+}
+
+// String describes the change.
+func (c Change) String() string {
+	from, to := c.From, c.To
+	if from == "" {
+		from = "none"
+	}
+	if to == "" {
+		to = "none"
+	}
+	return c.Name + " " + from + " → " + to
+}
+
+// Changes returns the version changes in the diffs of manifests, sorted by
+// dependency, and the added or removed lines that are not a dependency
+// version, e.g. replace directives. Dependencies whose line changed without
+// a version change are omitted.
+func Changes(patches []string) ([]Change, []string) {
+	changes := make(map[string]*Change)
+	var unparsed []string
+	for _, patch := range patches {
+		for _, line := range strings.Split(patch, "\n") {
+			if !changed(line) {
+				continue
+			}
+			m := requirement.FindStringSubmatch(strings.TrimRight(line, " \t\r"))
+			if m == nil {
+				unparsed = append(unparsed, line)
+				continue
+			}
+			c, ok := changes[m[2]]
+			if !ok {
+				c = &Change{Name: m[2]}
+				changes[m[2]] = c
+			}
+			version := m[3] + "." + m[4] + "." + m[5] + m[6]
+			parts := []int{atoi(m[3]), atoi(m[4]), atoi(m[5])}
+			c.prerelease = c.prerelease || m[6] != ""
+			if m[1] == "-" {
+				c.From, c.from = version, parts
+			} else {
+				c.To, c.to = version, parts
+			}
+		}
+	}
+	var all []Change
+	for _, c := range changes {
+		if c.From != c.To {
+			all = append(all, *c)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all, unparsed
+}
+
+// changed returns true if a diff line adds or removes a non-blank line.
+func changed(line string) bool {
+	if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+		return false
+	}
+	return (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")) && strings.TrimSpace(line[1:]) != ""
+}
+
+// PatchOnly returns true if every change is a patch level change.
+func PatchOnly(changes []Change) bool {
+	for _, c := range changes {
+		if c.Level() != Patch {
+			return false
+		}
+	}
+	return true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
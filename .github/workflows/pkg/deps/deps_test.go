@@ -0,0 +1,107 @@
+package deps
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		changes   []string
+		levels    []string
+		unparsed  []string
+		patchOnly bool
+	}{
+		{
+			name:      "go.mod require block",
+			patch:     "@@ -3,7 +3,7 @@\n require (\n-\tgithub.com/a/b v1.2.3\n+\tgithub.com/a/b v1.2.4\n-\tgithub.com/c/d v0.1.0 // indirect\n+\tgithub.com/c/d v0.2.0 // indirect\n )",
+			changes:   []string{"github.com/a/b 1.2.3 → 1.2.4", "github.com/c/d 0.1.0 → 0.2.0"},
+			levels:    []string{Patch, Minor},
+			patchOnly: false,
+		},
+		{
+			name:      "go.mod single require",
+			patch:     "-require github.com/a/b v1.2.3\n+require github.com/a/b v1.2.9",
+			changes:   []string{"github.com/a/b 1.2.3 → 1.2.9"},
+			levels:    []string{Patch},
+			patchOnly: true,
+		},
+		{
+			name:      "package.json",
+			patch:     "   \"dependencies\": {\n-    \"lodash\": \"^4.17.20\",\n+    \"lodash\": \"^4.17.21\",\n-    \"react\": \"~17.0.2\"\n+    \"react\": \"~18.0.0\"\n   }",
+			changes:   []string{"lodash 4.17.20 → 4.17.21", "react 17.0.2 → 18.0.0"},
+			levels:    []string{Patch, Major},
+			patchOnly: false,
+		},
+		{
+			name:      "added dependency",
+			patch:     "+\tgithub.com/a/b v1.0.0",
+			changes:   []string{"github.com/a/b none → 1.0.0"},
+			levels:    []string{Unknown},
+			patchOnly: false,
+		},
+		{
+			name:      "pseudo-version",
+			patch:     "-\tgithub.com/a/b v1.2.3\n+\tgithub.com/a/b v1.2.4-0.20210101000000-abcdef123456",
+			changes:   []string{"github.com/a/b 1.2.3 → 1.2.4-0.20210101000000-abcdef123456"},
+			levels:    []string{Unknown},
+			patchOnly: false,
+		},
+		{
+			name:      "replace directive",
+			patch:     "-\tgithub.com/a/b v1.2.3\n+\tgithub.com/a/b v1.2.4\n+replace github.com/a/b => github.com/evil/b v1.2.4",
+			changes:   []string{"github.com/a/b 1.2.3 → 1.2.4"},
+			levels:    []string{Patch},
+			unparsed:  []string{"+replace github.com/a/b => github.com/evil/b v1.2.4"},
+			patchOnly: true,
+		},
+		{
+			name:      "replace block",
+			patch:     "+replace (\n+\tgithub.com/a/b => ../b\n+\tgithub.com/c/d v1.0.0 => github.com/evil/d v1.0.1\n+)",
+			unparsed:  []string{"+replace (", "+\tgithub.com/a/b => ../b", "+\tgithub.com/c/d v1.0.0 => github.com/evil/d v1.0.1", "+)"},
+			patchOnly: true,
+		},
+		{
+			name:     "trailing text",
+			patch:    "-\tgithub.com/a/b v1.2.3\n+\tgithub.com/a/b v1.2.4 // see github.com/evil",
+			changes:  []string{"github.com/a/b 1.2.3 → none"},
+			levels:   []string{Unknown},
+			unparsed: []string{"+\tgithub.com/a/b v1.2.4 // see github.com/evil"},
+		},
+		{
+			name:      "blank lines and context",
+			patch:     "@@ -1,3 +1,4 @@\n module example.com/m\n+\n go 1.16",
+			patchOnly: true,
+		},
+		{
+			name:      "go directive",
+			patch:     "-go 1.16\n+go 1.17",
+			unparsed:  []string{"-go 1.16", "+go 1.17"},
+			patchOnly: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, unparsed := Changes([]string{tt.patch})
+			var descriptions, levels []string
+			for _, c := range changes {
+				descriptions = append(descriptions, c.String())
+				levels = append(levels, c.Level())
+			}
+			if !reflect.DeepEqual(descriptions, tt.changes) {
+				t.Errorf("changes = %q, want %q", descriptions, tt.changes)
+			}
+			if !reflect.DeepEqual(levels, tt.levels) {
+				t.Errorf("levels = %q, want %q", levels, tt.levels)
+			}
+			if !reflect.DeepEqual(unparsed, tt.unparsed) {
+				t.Errorf("unparsed = %q, want %q", unparsed, tt.unparsed)
+			}
+			if got := PatchOnly(changes); got != tt.patchOnly {
+				t.Errorf("PatchOnly() = %v, want %v", got, tt.patchOnly)
+			}
+		})
+	}
+}
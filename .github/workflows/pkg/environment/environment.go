@@ -0,0 +1,188 @@
+// Package environment reads the context a GitHub Actions workflow run was
+// triggered in: the repository, the event and, for pull request events, the
+// pull request itself.
+package environment
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/v37/github"
+)
+
+const (
+	// PullRequestTarget is the event triggered for activity on a pull
+	// request, run in the context of the base repository.
+	PullRequestTarget = "pull_request_target"
+	// PullRequest is the event triggered for activity on a pull request.
+	PullRequest = "pull_request"
+	// PullRequestReview is the event triggered when a review is submitted,
+	// edited or dismissed.
+	PullRequestReview = "pull_request_review"
+	// IssueComment is the event triggered for activity on a comment on an
+	// issue or pull request.
+	IssueComment = "issue_comment"
+
+	// Synchronize is the action of a pull request event triggered by new
+	// commits being pushed to the pull request branch.
+	Synchronize = "synchronize"
+)
+
+// Environment is the context the bot is running in.
+type Environment struct {
+	// Organization is the owner of the repository.
+	Organization string
+	// Repository is the name of the repository.
+	Repository string
+	// EventName is the name of the event that triggered the workflow.
+	EventName string
+	// Action is the activity type of the event, e.g. "opened".
+	Action string
+	// Metadata describes the pull request the event refers to. It is nil
+	// for events that do not refer to a pull request.
+	Metadata *Metadata
+	// Comment is the comment an issue_comment event refers to.
+	Comment *Comment
+}
+
+// Comment is a comment on an issue or pull request.
+type Comment struct {
+	// ID identifies the comment.
+	ID int64
+	// Body is the text of the comment.
+	Body string
+	// Author is the login of the commenter.
+	Author string
+	// AuthorType is the account type of the commenter, e.g. "Bot".
+	AuthorType string
+	// Number is the number of the issue or pull request.
+	Number int
+	// PullRequest is true if the comment is on a pull request.
+	PullRequest bool
+}
+
+// Metadata describes a pull request.
+type Metadata struct {
+	// Number is the pull request number.
+	Number int
+	// Author is the login of the pull request author.
+	Author string
+	// AuthorType is the account type of the author, e.g. "User" or "Bot".
+	AuthorType string
+	// AuthorAssociation is the author's relationship with the repository,
+	// e.g. MEMBER or CONTRIBUTOR.
+	AuthorAssociation string
+	// HeadSHA is the commit at the head of the pull request branch.
+	HeadSHA string
+	// HeadRef is the name of the pull request branch.
+	HeadRef string
+	// BaseRef is the name of the branch the pull request targets.
+	BaseRef string
+	// Labels are the names of the labels on the pull request.
+	Labels []string
+	// Title is the title of the pull request.
+	Title string
+	// Body is the description of the pull request.
+	Body string
+	// Draft is true if the pull request is a draft.
+	Draft bool
+}
+
+// New reads the environment from the variables GitHub Actions sets for
+// every workflow run.
+func New() (*Environment, error) {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	parts := strings.Split(repository, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid GITHUB_REPOSITORY %q", repository)
+	}
+	env := &Environment{
+		Organization: parts[0],
+		Repository:   parts[1],
+		EventName:    os.Getenv("GITHUB_EVENT_NAME"),
+	}
+
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return env, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading event payload: %w", err)
+	}
+	if err := env.parseEvent(data); err != nil {
+		return nil, fmt.Errorf("parsing %v event payload: %w", env.EventName, err)
+	}
+	return env, nil
+}
+
+// parseEvent extracts the action and the pull request or comment from an
+// event payload.
+func (e *Environment) parseEvent(data []byte) error {
+	var pr *github.PullRequest
+	switch e.EventName {
+	case PullRequestTarget, PullRequest:
+		var event github.PullRequestEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		e.Action = event.GetAction()
+		pr = event.PullRequest
+	case PullRequestReview:
+		var event github.PullRequestReviewEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		e.Action = event.GetAction()
+		pr = event.PullRequest
+	case IssueComment:
+		var event github.IssueCommentEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+		e.Action = event.GetAction()
+		if event.Comment == nil || event.Issue == nil {
+			return fmt.Errorf("missing comment")
+		}
+		e.Comment = &Comment{
+			ID:          event.Comment.GetID(),
+			Body:        event.Comment.GetBody(),
+			Author:      event.Comment.GetUser().GetLogin(),
+			AuthorType:  event.Comment.GetUser().GetType(),
+			Number:      event.Issue.GetNumber(),
+			PullRequest: event.Issue.IsPullRequest(),
+		}
+		return nil
+	default:
+		return nil
+	}
+	if pr == nil {
+		return fmt.Errorf("missing pull request")
+	}
+	e.Metadata = NewMetadata(pr)
+	return nil
+}
+
+// NewMetadata describes the given pull request.
+func NewMetadata(pr *github.PullRequest) *Metadata {
+	var labels []string
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
+	}
+	return &Metadata{
+		Number:            pr.GetNumber(),
+		Author:            pr.GetUser().GetLogin(),
+		AuthorType:        pr.GetUser().GetType(),
+		AuthorAssociation: pr.GetAuthorAssociation(),
+		HeadSHA:           pr.GetHead().GetSHA(),
+		HeadRef:           pr.GetHead().GetRef(),
+		BaseRef:           pr.GetBase().GetRef(),
+		Labels:            labels,
+		Title:             pr.GetTitle(),
+		Body:              pr.GetBody(),
+		Draft:             pr.GetDraft(),
+	}
+}
@@ -0,0 +1,143 @@
+// Package expertise scores how familiar people are with paths in a
+// repository from the history of commits to them.
+package expertise
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// HalfLife is the age at which a commit counts half as much as one made
+// now.
+const HalfLife = 90 * 24 * time.Hour
+
+// Commit is a commit to a path.
+type Commit struct {
+	// SHA identifies the commit. Histories cached before it was recorded
+	// have none.
+	SHA string `json:"sha,omitempty"`
+	// Author is the login of the commit's author.
+	Author string `json:"author"`
+	// Date is when the commit was authored.
+	Date time.Time `json:"date"`
+}
+
+// Weight is how much a commit of a given age counts towards its author's
+// expertise: 1 for a commit made now, halving every HalfLife.
+func Weight(age time.Duration) float64 {
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(HalfLife))
+}
+
+// Expert is a person and their expertise score.
+type Expert struct {
+	Login string
+	Score float64
+}
+
+// Score sums the recency weighted commits of each author across paths and
+// returns the authors accepted by eligible, best first. Authors are counted
+// once per commit, however many of the paths it touched. Commits without a
+// SHA count once per path.
+func Score(history map[string][]Commit, now time.Time, eligible func(login string) bool) []Expert {
+	scores := make(map[string]float64)
+	counted := make(map[string]bool)
+	for _, commits := range history {
+		for _, commit := range commits {
+			if commit.Author == "" || !eligible(commit.Author) {
+				continue
+			}
+			if commit.SHA != "" {
+				if counted[commit.SHA] {
+					continue
+				}
+				counted[commit.SHA] = true
+			}
+			scores[commit.Author] += Weight(now.Sub(commit.Date))
+		}
+	}
+	experts := make([]Expert, 0, len(scores))
+	for login, score := range scores {
+		experts = append(experts, Expert{Login: login, Score: score})
+	}
+	sort.Slice(experts, func(i, j int) bool {
+		if experts[i].Score != experts[j].Score {
+			return experts[i].Score > experts[j].Score
+		}
+		return experts[i].Login < experts[j].Login
+	})
+	return experts
+}
+
+// Cache keeps the commit history of paths between runs.
+type Cache struct {
+	// Paths maps a path to its cached history.
+	Paths map[string]*Entry `json:"paths"`
+}
+
+// Entry is the cached history of a path.
+type Entry struct {
+	// Fetched is when the history was fetched.
+	Fetched time.Time `json:"fetched"`
+	// Commits are the recent commits to the path.
+	Commits []Commit `json:"commits"`
+}
+
+// Get returns the cached history of a path if it was fetched less than
+// maxAge before now.
+func (c *Cache) Get(path string, now time.Time, maxAge time.Duration) ([]Commit, bool) {
+	entry, ok := c.Paths[path]
+	if !ok || now.Sub(entry.Fetched) >= maxAge {
+		return nil, false
+	}
+	return entry.Commits, true
+}
+
+// Put caches the history of a path.
+func (c *Cache) Put(path string, now time.Time, commits []Commit) {
+	if c.Paths == nil {
+		c.Paths = make(map[string]*Entry)
+	}
+	c.Paths[path] = &Entry{Fetched: now, Commits: commits}
+}
+
+// Prune removes entries fetched maxAge or longer before now.
+func (c *Cache) Prune(now time.Time, maxAge time.Duration) {
+	for path, entry := range c.Paths {
+		if now.Sub(entry.Fetched) >= maxAge {
+			delete(c.Paths, path)
+		}
+	}
+}
+
+// ReadCache reads a cache file. A missing file is an empty cache.
+func ReadCache(path string) (*Cache, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Cache{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Cache
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing %v: %w", path, err)
+	}
+	return &c, nil
+}
+
+// WriteCache writes a cache file.
+func WriteCache(path string, c *Cache) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,57 @@
+package expertise
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	history := map[string][]Commit{
+		"a.go": {
+			{SHA: "1", Author: "alice", Date: now},
+			{SHA: "2", Author: "bob", Date: now.Add(-HalfLife)},
+			{SHA: "3", Author: "mallory", Date: now},
+		},
+		// Commit 1 touched both files and counts once.
+		"b.go": {
+			{SHA: "1", Author: "alice", Date: now},
+			{SHA: "4", Author: "bob", Date: now.Add(-HalfLife)},
+			{Author: "carol", Date: now.Add(-2 * HalfLife)},
+		},
+		"c.go": {
+			{Author: "carol", Date: now.Add(-2 * HalfLife)},
+			{SHA: "5", Author: "", Date: now},
+		},
+	}
+	eligible := func(login string) bool { return login != "mallory" }
+	got := Score(history, now, eligible)
+	want := []Expert{
+		{Login: "alice", Score: 1},
+		{Login: "bob", Score: 1},
+		{Login: "carol", Score: 0.5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Score() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Login != want[i].Login || math.Abs(got[i].Score-want[i].Score) > 1e-9 {
+			t.Errorf("Score()[%v] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var c Cache
+	commits := []Commit{{SHA: "1", Author: "alice", Date: now}}
+	c.Put("a.go", now, commits)
+	if got, ok := c.Get("a.go", now.Add(time.Hour), 24*time.Hour); !ok || !reflect.DeepEqual(got, commits) {
+		t.Errorf("Get() = %v, %v, want %v, true", got, ok, commits)
+	}
+	if _, ok := c.Get("a.go", now.Add(24*time.Hour), 24*time.Hour); ok {
+		t.Errorf("Get() of an expired entry succeeded")
+	}
+}
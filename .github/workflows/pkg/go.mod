@@ -2,4 +2,8 @@ module github.com/gravitational/gh-actions-poc/.github/workflows/pkg
 
 go 1.16
 
-require github.com/google/go-github/v37 v37.0.0
+require (
+	github.com/google/go-github/v37 v37.0.0
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
+	gopkg.in/yaml.v2 v2.4.0
+)
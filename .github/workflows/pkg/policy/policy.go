@@ -0,0 +1,209 @@
+// Package policy evaluates the reviews of a pull request against the
+// approvals it requires.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Approved is the state of an approving review.
+	Approved = "APPROVED"
+	// ChangesRequested is the state of a review requesting changes.
+	ChangesRequested = "CHANGES_REQUESTED"
+)
+
+// Requirement is a rule a pull request must satisfy to be approved.
+type Requirement struct {
+	// Name describes the requirement in check output.
+	Name string
+	// Reviewers are the users whose approval counts towards the requirement.
+	Reviewers []string
+	// Approvals is the number of approvals required.
+	Approvals int
+}
+
+// Review is the latest review state of a single reviewer.
+type Review struct {
+	// Author is the login of the reviewer.
+	Author string
+	// State is the state of the review, e.g. APPROVED.
+	State string
+	// Expired is set if the review is an approval that is too old to
+	// count.
+	Expired bool
+	// Writer is set if the reviewer has write access to the repository.
+	Writer bool
+}
+
+// Result is the outcome of evaluating a single requirement.
+type Result struct {
+	Requirement Requirement
+	// Approvers are the reviewers who approved and count towards the
+	// requirement.
+	Approvers []string
+}
+
+// Satisfied returns true if the requirement has enough approvals.
+func (r Result) Satisfied() bool {
+	return len(r.Approvers) >= r.Requirement.Approvals
+}
+
+// String describes the result for check output.
+func (r Result) String() string {
+	if r.Satisfied() {
+		if len(r.Approvers) == 0 {
+			return fmt.Sprintf("%v: no approvals needed", r.Requirement.Name)
+		}
+		return fmt.Sprintf("%v: approved by %v", r.Requirement.Name, strings.Join(r.Approvers, ", "))
+	}
+	var waiting []string
+	for _, reviewer := range r.Requirement.Reviewers {
+		if !contains(r.Approvers, reviewer) {
+			waiting = append(waiting, reviewer)
+		}
+	}
+	return fmt.Sprintf("%v: %v of %v approvals, missing %v from %v",
+		r.Requirement.Name, len(r.Approvers), r.Requirement.Approvals,
+		r.Requirement.Approvals-len(r.Approvers), describeReviewers(waiting))
+}
+
+// Condition is a requirement other than approvals, e.g. a check that must
+// pass, evaluated by the caller.
+type Condition struct {
+	// Name describes the condition in check output.
+	Name string
+	// Met is true if the condition holds.
+	Met bool
+}
+
+// Evaluation is the outcome of evaluating every requirement of a policy.
+type Evaluation struct {
+	Results []Result
+	// Conditions are the other requirements of the policy.
+	Conditions []Condition
+	// ChangesRequested are the reviewers whose latest review requests
+	// changes and blocks approval.
+	ChangesRequested []string
+	// Expired are the reviewers whose approval expired and does not
+	// count.
+	Expired []string
+}
+
+// Approved returns true if every requirement is satisfied and nobody has
+// outstanding requested changes.
+func (e Evaluation) Approved() bool {
+	if len(e.ChangesRequested) > 0 {
+		return false
+	}
+	for _, r := range e.Results {
+		if !r.Satisfied() {
+			return false
+		}
+	}
+	for _, c := range e.Conditions {
+		if !c.Met {
+			return false
+		}
+	}
+	return true
+}
+
+// Approvers returns the reviewers whose approval counts towards any
+// requirement, sorted.
+func (e Evaluation) Approvers() []string {
+	var approvers []string
+	for _, r := range e.Results {
+		for _, approver := range r.Approvers {
+			if !contains(approvers, approver) {
+				approvers = append(approvers, approver)
+			}
+		}
+	}
+	sort.Strings(approvers)
+	return approvers
+}
+
+// Explain describes why the pull request is or is not approved, one line
+// per requirement, and lists expired approvals.
+func (e Evaluation) Explain() string {
+	var lines []string
+	for _, r := range e.Results {
+		mark := "✓"
+		if !r.Satisfied() {
+			mark = "✗"
+		}
+		lines = append(lines, fmt.Sprintf("%v %v", mark, r))
+	}
+	for _, c := range e.Conditions {
+		mark := "✓"
+		if !c.Met {
+			mark = "✗"
+		}
+		lines = append(lines, fmt.Sprintf("%v %v", mark, c.Name))
+	}
+	if len(e.ChangesRequested) > 0 {
+		lines = append(lines, fmt.Sprintf("✗ changes requested by %v", strings.Join(e.ChangesRequested, ", ")))
+	}
+	if len(e.Expired) > 0 {
+		lines = append(lines, fmt.Sprintf("⚠ expired approvals by %v no longer count", strings.Join(e.Expired, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Evaluate checks the latest review of each reviewer against the
+// requirements. Expired approvals do not count, and only reviewers of a
+// requirement or with write access can block approval by requesting
+// changes.
+func Evaluate(requirements []Requirement, reviews []Review) Evaluation {
+	var e Evaluation
+	eligible := make(map[string]bool)
+	for _, requirement := range requirements {
+		for _, reviewer := range requirement.Reviewers {
+			eligible[reviewer] = true
+		}
+	}
+	approvers := make(map[string]bool)
+	for _, review := range reviews {
+		switch {
+		case review.State == Approved && review.Expired:
+			e.Expired = append(e.Expired, review.Author)
+		case review.State == Approved:
+			approvers[review.Author] = true
+		case review.State == ChangesRequested && (eligible[review.Author] || review.Writer):
+			e.ChangesRequested = append(e.ChangesRequested, review.Author)
+		}
+	}
+	sort.Strings(e.ChangesRequested)
+	sort.Strings(e.Expired)
+
+	for _, requirement := range requirements {
+		result := Result{Requirement: requirement}
+		for _, reviewer := range requirement.Reviewers {
+			if approvers[reviewer] && !contains(result.Approvers, reviewer) {
+				result.Approvers = append(result.Approvers, reviewer)
+			}
+		}
+		sort.Strings(result.Approvers)
+		e.Results = append(e.Results, result)
+	}
+	return e
+}
+
+func describeReviewers(reviewers []string) string {
+	if len(reviewers) == 0 {
+		return "nobody (no eligible reviewers configured)"
+	}
+	return strings.Join(reviewers, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	requirements := []Requirement{
+		{Name: "reviewers", Reviewers: []string{"alice", "bob", "carol"}, Approvals: 2},
+		{Name: "security", Reviewers: []string{"dave"}, Approvals: 1},
+	}
+	tests := []struct {
+		name             string
+		reviews          []Review
+		approvers        [][]string
+		changesRequested []string
+		expired          []string
+		approved         bool
+	}{
+		{
+			name:      "no reviews",
+			approvers: [][]string{nil, nil},
+		},
+		{
+			name: "approved",
+			reviews: []Review{
+				{Author: "alice", State: Approved},
+				{Author: "bob", State: Approved},
+				{Author: "dave", State: Approved},
+			},
+			approvers: [][]string{{"alice", "bob"}, {"dave"}},
+			approved:  true,
+		},
+		{
+			name: "approval from outside the requirement",
+			reviews: []Review{
+				{Author: "alice", State: Approved},
+				{Author: "dave", State: Approved},
+				{Author: "mallory", State: Approved},
+			},
+			approvers: [][]string{{"alice"}, {"dave"}},
+		},
+		{
+			name: "expired approval",
+			reviews: []Review{
+				{Author: "alice", State: Approved},
+				{Author: "bob", State: Approved, Expired: true},
+				{Author: "dave", State: Approved},
+			},
+			approvers: [][]string{{"alice"}, {"dave"}},
+			expired:   []string{"bob"},
+		},
+		{
+			name: "changes requested by a reviewer",
+			reviews: []Review{
+				{Author: "alice", State: Approved},
+				{Author: "bob", State: Approved},
+				{Author: "carol", State: ChangesRequested},
+				{Author: "dave", State: Approved},
+			},
+			approvers:        [][]string{{"alice", "bob"}, {"dave"}},
+			changesRequested: []string{"carol"},
+		},
+		{
+			name: "changes requested by a writer",
+			reviews: []Review{
+				{Author: "alice", State: Approved},
+				{Author: "bob", State: Approved},
+				{Author: "dave", State: Approved},
+				{Author: "erin", State: ChangesRequested, Writer: true},
+			},
+			approvers:        [][]string{{"alice", "bob"}, {"dave"}},
+			changesRequested: []string{"erin"},
+		},
+		{
+			name: "changes requested by anyone else",
+			reviews: []Review{
+				{Author: "alice", State: Approved},
+				{Author: "bob", State: Approved},
+				{Author: "dave", State: Approved},
+				{Author: "mallory", State: ChangesRequested},
+			},
+			approvers: [][]string{{"alice", "bob"}, {"dave"}},
+			approved:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Evaluate(requirements, tt.reviews)
+			for i, result := range e.Results {
+				if !reflect.DeepEqual(result.Approvers, tt.approvers[i]) {
+					t.Errorf("approvers of %v = %v, want %v", result.Requirement.Name, result.Approvers, tt.approvers[i])
+				}
+			}
+			if !reflect.DeepEqual(e.ChangesRequested, tt.changesRequested) {
+				t.Errorf("ChangesRequested = %v, want %v", e.ChangesRequested, tt.changesRequested)
+			}
+			if !reflect.DeepEqual(e.Expired, tt.expired) {
+				t.Errorf("Expired = %v, want %v", e.Expired, tt.expired)
+			}
+			if got := e.Approved(); got != tt.approved {
+				t.Errorf("Approved() = %v, want %v\n%v", got, tt.approved, e.Explain())
+			}
+		})
+	}
+}
+
+func TestApprovedConditions(t *testing.T) {
+	e := Evaluate([]Requirement{{Name: "none", Approvals: 0}}, nil)
+	if !e.Approved() {
+		t.Fatalf("Approved() = false without requirements:\n%v", e.Explain())
+	}
+	e.Conditions = []Condition{{Name: "checks pass", Met: true}, {Name: "co-authors known"}}
+	if e.Approved() {
+		t.Errorf("Approved() = true with an unmet condition:\n%v", e.Explain())
+	}
+	e.Conditions[1].Met = true
+	if !e.Approved() {
+		t.Errorf("Approved() = false with every condition met:\n%v", e.Explain())
+	}
+}
+
+func TestApprovers(t *testing.T) {
+	requirements := []Requirement{
+		{Name: "reviewers", Reviewers: []string{"carol", "alice", "bob"}, Approvals: 2},
+		{Name: "security", Reviewers: []string{"alice", "dave"}, Approvals: 1},
+	}
+	reviews := []Review{
+		{Author: "alice", State: Approved},
+		{Author: "bob", State: Approved, Expired: true},
+		{Author: "carol", State: ChangesRequested},
+		{Author: "dave", State: Approved},
+		{Author: "mallory", State: Approved},
+	}
+	want := []string{"alice", "dave"}
+	if got := Evaluate(requirements, reviews).Approvers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Approvers() = %v, want %v", got, want)
+	}
+}
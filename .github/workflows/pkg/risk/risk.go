@@ -0,0 +1,95 @@
+// Package risk scores how risky a pull request is from the files it
+// changes, so that riskier changes can require more reviewers.
+package risk
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+)
+
+const (
+	// linesPerPoint is the number of changed lines worth one point.
+	linesPerPoint = 100
+	// filesPerPoint is the number of changed files worth one point.
+	filesPerPoint = 10
+	// sensitivePoints are added when any sensitive path is changed.
+	sensitivePoints = 10
+	// maxSensitivePaths is the number of sensitive paths listed in a
+	// description.
+	maxSensitivePaths = 3
+)
+
+// File is a file changed by a pull request.
+type File struct {
+	// Path is the path of the file relative to the repository root.
+	Path string
+	// Previous is the path the file was renamed from, if it was renamed.
+	Previous string
+	// Changes is the number of lines added and removed.
+	Changes int
+}
+
+// Score is the risk score of a pull request and what it is made of.
+type Score struct {
+	// Value is the score: a point per 100 changed lines and per 10
+	// changed files, rounded up, plus 10 if a sensitive path is changed.
+	Value int
+	// Lines is the number of lines added and removed.
+	Lines int
+	// Files is the number of files changed.
+	Files int
+	// Sensitive are the changed files matching a sensitive path pattern.
+	Sensitive []string
+}
+
+// Compute scores the files changed by a pull request. Files matching any
+// of the sensitive rules, before or after a rename, make the change
+// sensitive.
+func Compute(files []File, sensitive []*codeowners.Rule) Score {
+	var s Score
+	for _, file := range files {
+		s.Files++
+		s.Lines += file.Changes
+		if matchAny(sensitive, file.Path) || (file.Previous != "" && matchAny(sensitive, file.Previous)) {
+			s.Sensitive = append(s.Sensitive, file.Path)
+		}
+	}
+	s.Value = ceilDiv(s.Lines, linesPerPoint) + ceilDiv(s.Files, filesPerPoint)
+	if len(s.Sensitive) > 0 {
+		s.Value += sensitivePoints
+	}
+	return s
+}
+
+// String describes the score for check output.
+func (s Score) String() string {
+	description := fmt.Sprintf("risk score %v: %v lines changed in %v files", s.Value, s.Lines, s.Files)
+	if len(s.Sensitive) == 0 {
+		return description
+	}
+	return fmt.Sprintf("%v, touches sensitive paths %v", description, DescribePaths(s.Sensitive))
+}
+
+// DescribePaths lists the first few of a set of sensitive paths and how
+// many more there are.
+func DescribePaths(paths []string) string {
+	if len(paths) <= maxSensitivePaths {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%v and %v more", strings.Join(paths[:maxSensitivePaths], ", "), len(paths)-maxSensitivePaths)
+}
+
+func matchAny(rules []*codeowners.Rule, path string) bool {
+	for _, rule := range rules {
+		if rule.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
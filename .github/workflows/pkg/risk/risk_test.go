@@ -0,0 +1,74 @@
+package risk
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gravitational/gh-actions-poc/.github/workflows/pkg/codeowners"
+)
+
+func TestCompute(t *testing.T) {
+	rule, err := codeowners.Compile(".github/workflows/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sensitive := []*codeowners.Rule{rule}
+	tests := []struct {
+		name      string
+		files     []File
+		value     int
+		sensitive []string
+	}{
+		{name: "no files"},
+		{
+			name:  "ordinary change",
+			files: []File{{Path: "lib/a.go", Changes: 150}, {Path: "lib/b.go", Changes: 1}},
+			value: 3,
+		},
+		{
+			name:      "sensitive change",
+			files:     []File{{Path: ".github/workflows/check.yml", Changes: 1}},
+			value:     12,
+			sensitive: []string{".github/workflows/check.yml"},
+		},
+		{
+			name:      "renamed out of a sensitive path",
+			files:     []File{{Path: "check.yml", Previous: ".github/workflows/check.yml"}},
+			value:     11,
+			sensitive: []string{"check.yml"},
+		},
+		{
+			name:  "renamed within ordinary paths",
+			files: []File{{Path: "lib/b.go", Previous: "lib/a.go"}},
+			value: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := Compute(tt.files, sensitive)
+			if score.Value != tt.value {
+				t.Errorf("Value = %v, want %v", score.Value, tt.value)
+			}
+			if !reflect.DeepEqual(score.Sensitive, tt.sensitive) {
+				t.Errorf("Sensitive = %v, want %v", score.Sensitive, tt.sensitive)
+			}
+		})
+	}
+}
+
+func TestDescribePaths(t *testing.T) {
+	tests := []struct {
+		paths []string
+		want  string
+	}{
+		{paths: nil, want: ""},
+		{paths: []string{"a"}, want: "a"},
+		{paths: []string{"a", "b", "c"}, want: "a, b, c"},
+		{paths: []string{"a", "b", "c", "d", "e"}, want: "a, b, c and 2 more"},
+	}
+	for _, tt := range tests {
+		if got := DescribePaths(tt.paths); got != tt.want {
+			t.Errorf("DescribePaths(%q) = %q, want %q", tt.paths, got, tt.want)
+		}
+	}
+}